@@ -6,22 +6,79 @@ import (
 	"github.com/akutz/gofsutil"
 	csi "github.com/onlineque/kvmCsiDriver/csi_proto"
 	sa "github.com/onlineque/kvmCsiDriver/storageagent_proto"
+	"golang.org/x/sys/unix"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
+	"io"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"log"
+	"maps"
 	"net"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 )
 
 const ImplementMe = "implement me"
 
+// KvmDomainLabel is the node label holding the name of the KVM domain running the node
+const KvmDomainLabel = "example.clew.cz/kvm-domain"
+
+// PublishContextDevice is the publish context key holding the device name of the attached disk
+const PublishContextDevice = "device"
+
+// PublishContextSerial is the publish context key holding the serial of the attached disk
+const PublishContextSerial = "serial"
+
+// PublishContextReadonly is the publish context key set to "true" when the disk is attached read-only
+const PublishContextReadonly = "readonly"
+
+// deviceTimeout limits waiting for udev of the guest to announce a newly attached disk
+const deviceTimeout = 30 * time.Second
+
+// StorageClass parameters tuning the disk of a volume, they can be changed through a VolumeAttributesClass
+const (
+	TotalBytesSecParameter = "totalBytesSec"
+	ReadBytesSecParameter  = "readBytesSec"
+	WriteBytesSecParameter = "writeBytesSec"
+	TotalIopsSecParameter  = "totalIopsSec"
+	ReadIopsSecParameter   = "readIopsSec"
+	WriteIopsSecParameter  = "writeIopsSec"
+	CacheModeParameter     = "cacheMode"
+)
+
+// CloneTypeParameter is the StorageClass parameter choosing between "full" (default) and "linked" clones
+const CloneTypeParameter = "cloneType"
+
+// StorageClass parameters choosing the format and the allocation of the images, they can't be changed later
+const (
+	FormatParameter        = "format"
+	PreallocationParameter = "preallocation"
+	ClusterSizeParameter   = "clusterSize"
+	LazyRefcountsParameter = "lazyRefcounts"
+)
+
+// MkfsOptionsParameter is the StorageClass parameter holding extra arguments for mkfs, e.g. "-m 0"
+const MkfsOptionsParameter = "mkfsOptions"
+
+// DefaultFsType is used when neither the StorageClass nor the volume capability ask for a filesystem type
+const DefaultFsType = "ext4"
+
+var supportedFsTypes = []string{"ext4", "xfs", "btrfs"}
+
 type controllerServer struct {
 	csi.UnimplementedControllerServer
 }
@@ -72,6 +129,13 @@ func (ids *identityServer) GetPluginCapabilities(_ context.Context, _ *csi.GetPl
 					},
 				},
 			},
+			{
+				Type: &csi.PluginCapability_VolumeExpansion_{
+					VolumeExpansion: &csi.PluginCapability_VolumeExpansion{
+						Type: csi.PluginCapability_VolumeExpansion_ONLINE,
+					},
+				},
+			},
 		},
 	}, nil
 }
@@ -81,58 +145,280 @@ func (ids *identityServer) Probe(_ context.Context, _ *csi.ProbeRequest) (*csi.P
 	return &csi.ProbeResponse{Ready: &wrappers.BoolValue{Value: true}}, nil
 }
 
-func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
-	// mounting the volume should be here
-	log.Print("NodePublishVolume called")
+func (ns *nodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolumeRequest) (*csi.NodeStageVolumeResponse, error) {
+	log.Print("NodeStageVolume called")
 	volumeID := req.VolumeId
-	targetPath := req.TargetPath
+	stagingTargetPath := req.StagingTargetPath
 	log.Printf("- volumeId: %s", volumeID)
-	log.Printf("  targetPath: %s", targetPath)
+	log.Printf("  stagingTargetPath: %s", stagingTargetPath)
 
-	// attach volume to this node
-	config, err := rest.InClusterConfig()
+	if volumeID == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume ID missing in request")
+	}
+	if stagingTargetPath == "" {
+		return nil, status.Error(codes.InvalidArgument, "staging target path missing in request")
+	}
+
+	// the disk has been attached to this node by ControllerPublishVolume already
+	device := req.GetPublishContext()[PublishContextDevice]
+	if device == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%s missing in the publish context", PublishContextDevice)
+	}
+
+	if req.GetVolumeCapability().GetBlock() != nil {
+		// raw block volumes must not be formatted, NodePublishVolume bind-mounts the device itself
+		log.Printf("volume %s is a block volume, nothing to stage", volumeID)
+		return &csi.NodeStageVolumeResponse{}, nil
+	}
+
+	devicePath, err := findDevice(ctx, device, req.GetPublishContext()[PublishContextSerial])
 	if err != nil {
 		return nil, err
 	}
-	clientset, err := kubernetes.NewForConfig(config)
+
+	mount, err := findMount(ctx, stagingTargetPath)
 	if err != nil {
 		return nil, err
 	}
+	if mount != nil {
+		if mount.Device != devicePath {
+			return nil, status.Errorf(codes.AlreadyExists, "staging path %s holds %s instead of %s", stagingTargetPath, mount.Device, devicePath)
+		}
+		log.Printf("volume %s is staged at %s already", volumeID, stagingTargetPath)
+		return &csi.NodeStageVolumeResponse{}, nil
+	}
+
+	// create filesystem (first check if it's not there already ?)
+	// mount it into stagingTargetPath, the pods get it bind-mounted from there
+	log.Printf("checking filesystem on %s", devicePath)
+	// create mount directory
+	if _, err := os.Stat(stagingTargetPath); os.IsNotExist(err) {
+		err := os.MkdirAll(stagingTargetPath, 0755)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the staging directory: %w", err)
+		}
+		log.Printf("created staging directory: %s\n", stagingTargetPath)
+	}
+
+	mountVolume := req.GetVolumeCapability().GetMount()
+	fsType := mountVolume.GetFsType()
+	if fsType == "" {
+		fsType = DefaultFsType
+	}
+	if !slices.Contains(supportedFsTypes, fsType) {
+		return nil, status.Errorf(codes.InvalidArgument, "filesystem type %s is not supported", fsType)
+	}
+	mkfsOptions := strings.Fields(req.GetVolumeContext()[MkfsOptionsParameter])
+	mountFlags := mountVolume.GetMountFlags()
+	readonly := req.GetPublishContext()[PublishContextReadonly] == "true"
+	if readonly {
+		// the guest can't write to the disk, so the filesystem must not be mounted writable
+		mountFlags = append(slices.Clone(mountFlags), "ro")
+	}
 
-	nodeObj, err := clientset.CoreV1().Nodes().Get(context.TODO(), os.Getenv("NODE_ID"), metav1.GetOptions{})
+	err = formatAndMount(ctx, devicePath, stagingTargetPath, fsType, mkfsOptions, mountFlags, readonly)
 	if err != nil {
 		return nil, err
 	}
-	kvmDomain := nodeObj.Labels["example.clew.cz/kvm-domain"]
-	log.Printf("  kvmNode: %s", kvmDomain)
 
-	conn, err := grpc.NewClient(os.Getenv("STORAGEAGENT_TARGET"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	return &csi.NodeStageVolumeResponse{}, nil
+}
+
+// findDevice returns the device node of the disk with the serial. The guest kernel names the disks on its own,
+// so the device name chosen by libvirt is only used for the disks attached without a serial.
+func findDevice(ctx context.Context, device string, serial string) (string, error) {
+	if serial == "" {
+		return fmt.Sprintf("/dev/%s", device), nil
+	}
+
+	link := fmt.Sprintf("/dev/disk/by-id/scsi-0QEMU_QEMU_HARDDISK_%s", serial)
+	ctx, cancel := context.WithTimeout(ctx, deviceTimeout)
+	defer cancel()
+	for {
+		devicePath, err := filepath.EvalSymlinks(link)
+		if err == nil {
+			// never trust a stale link with a device which is about to be formatted
+			deviceSerial, err := readSerial(devicePath)
+			if err != nil {
+				return "", err
+			}
+			if deviceSerial != serial {
+				return "", status.Errorf(codes.FailedPrecondition, "%s has serial %q instead of %q", devicePath, deviceSerial, serial)
+			}
+			return devicePath, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", status.Errorf(codes.DeadlineExceeded, "disk with serial %s did not show up in %s", serial, link)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// readSerial returns the serial of the SCSI disk from its unit serial number VPD page
+func readSerial(devicePath string) (string, error) {
+	page, err := os.ReadFile(fmt.Sprintf("/sys/class/block/%s/device/vpd_pg80", filepath.Base(devicePath)))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the serial of %s: %w", devicePath, err)
+	}
+	// the serial follows the 4 bytes long page header
+	if len(page) < 4 {
+		return "", fmt.Errorf("invalid serial page of %s", devicePath)
+	}
+	return strings.Trim(string(page[4:]), " \x00"), nil
+}
+
+// formatAndMount creates a filesystem of fsType on the device unless there is one already and mounts it
+// at target. An existing filesystem of a different type is never reused nor overwritten, a read-only
+// device is never formatted.
+func formatAndMount(ctx context.Context, device string, target string, fsType string, mkfsOptions []string, mountFlags []string, readonly bool) error {
+	existingFsType, err := gofsutil.GetDiskFormat(ctx, device)
+	if err != nil {
+		return fmt.Errorf("failed to detect the filesystem on %s: %w", device, err)
+	}
+
+	switch {
+	case existingFsType == "" && readonly:
+		return status.Errorf(codes.FailedPrecondition, "%s is attached read-only and holds no filesystem", device)
+	case existingFsType == "":
+		args := slices.Clone(mkfsOptions)
+		if fsType == "ext4" {
+			// mkfs.ext4 asks for confirmation when given a whole disk
+			args = append(args, "-F")
+		}
+		args = append(args, device)
+		log.Printf("creating %s filesystem on %s", fsType, device)
+		output, err := exec.Command(fmt.Sprintf("mkfs.%s", fsType), args...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("mkfs.%s failed: %w, output: %s", fsType, err, output)
+		}
+	case existingFsType == fsType:
+		log.Printf("%s already holds a %s filesystem", device, fsType)
+	default:
+		return status.Errorf(codes.FailedPrecondition, "%s holds %s filesystem, %s was requested", device, existingFsType, fsType)
+	}
+
+	return gofsutil.Mount(ctx, device, target, fsType, mountFlags...)
+}
+
+// findMount returns the filesystem mounted at path, or nil if there is none
+func findMount(ctx context.Context, path string) (*gofsutil.Info, error) {
+	mounts, err := gofsutil.GetMounts(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close()
+	for i := range mounts {
+		if mounts[i].Path == path {
+			return &mounts[i], nil
+		}
+	}
+	return nil, nil
+}
 
-	c := sa.NewStorageAgentClient(conn)
+func (ns *nodeServer) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstageVolumeRequest) (*csi.NodeUnstageVolumeResponse, error) {
+	log.Print("NodeUnstageVolume called")
+	volumeID := req.VolumeId
+	stagingTargetPath := req.StagingTargetPath
+	log.Printf("- volumeId: %s", volumeID)
+	log.Printf("  stagingTargetPath: %s", stagingTargetPath)
 
-	// ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	// defer cancel()
+	if volumeID == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume ID missing in request")
+	}
+	if stagingTargetPath == "" {
+		return nil, status.Error(codes.InvalidArgument, "staging target path missing in request")
+	}
 
-	img, err := c.AttachVolume(ctx, &sa.VolumeRequest{
-		ImageId:    volumeID,
-		TargetPath: targetPath,
-		DomainName: kvmDomain,
-	})
+	mounts, err := gofsutil.GetMounts(ctx)
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("successfully attached volume %s to %s:%s", img.ImageId, kvmDomain, targetPath)
+	device := ""
+	for _, mount := range mounts {
+		if mount.Path == stagingTargetPath {
+			device = mount.Device
+			break
+		}
+	}
+	if device != "" {
+		// the disk may be unmounted only after the last pod stopped using it
+		for _, mount := range mounts {
+			if mount.Device == device && mount.Path != stagingTargetPath {
+				return nil, status.Errorf(codes.FailedPrecondition, "volume %s is still published at %s", volumeID, mount.Path)
+			}
+		}
 
-	// create filesystem (first check if it's not there already ?)
-	// mount it into targetPath
-	log.Printf("checking filesystem on /dev/%s", img.Device)
-	// create mount directory
-	if _, err := os.Stat(targetPath); os.IsNotExist(err) {
-		// Step 2: Create the directory along with any necessary parents
+		err = gofsutil.Unmount(ctx, stagingTargetPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	log.Printf("successfully unstaged volume %s from %s", volumeID, stagingTargetPath)
+
+	return &csi.NodeUnstageVolumeResponse{}, nil
+}
+
+func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
+	log.Print("NodePublishVolume called")
+	volumeID := req.VolumeId
+	stagingTargetPath := req.StagingTargetPath
+	targetPath := req.TargetPath
+	log.Printf("- volumeId: %s", volumeID)
+	log.Printf("  stagingTargetPath: %s", stagingTargetPath)
+	log.Printf("  targetPath: %s", targetPath)
+
+	if volumeID == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume ID missing in request")
+	}
+	if stagingTargetPath == "" {
+		return nil, status.Error(codes.InvalidArgument, "staging target path missing in request")
+	}
+	if targetPath == "" {
+		return nil, status.Error(codes.InvalidArgument, "target path missing in request")
+	}
+
+	mount, err := findMount(ctx, targetPath)
+	if err != nil {
+		return nil, err
+	}
+	if mount != nil {
+		log.Printf("volume %s is published at %s already", volumeID, targetPath)
+		return &csi.NodePublishVolumeResponse{}, nil
+	}
+
+	var opts []string
+	if req.Readonly {
+		opts = append(opts, "ro")
+	}
+
+	source := stagingTargetPath
+	if req.GetVolumeCapability().GetBlock() != nil {
+		// block volumes are not staged, the guest device node gets bind-mounted to a file at targetPath
+		device := req.GetPublishContext()[PublishContextDevice]
+		if device == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "%s missing in the publish context", PublishContextDevice)
+		}
+		source, err = findDevice(ctx, device, req.GetPublishContext()[PublishContextSerial])
+		if err != nil {
+			return nil, err
+		}
+
+		err = os.MkdirAll(filepath.Dir(targetPath), 0755)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the parent directory of the target file: %w", err)
+		}
+		targetFile, err := os.OpenFile(targetPath, os.O_CREATE, 0660)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the target file: %w", err)
+		}
+		targetFile.Close()
+	} else if _, err := os.Stat(targetPath); os.IsNotExist(err) {
+		// create mount directory
 		err := os.MkdirAll(targetPath, 0755) // 0755 gives read, write, and execute permissions to the owner, and read + execute permissions to others
 		if err != nil {
 			return nil, fmt.Errorf("failed to create the mountpoint directory: %w", err)
@@ -140,10 +426,11 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 		log.Printf("created mount point directory: %s\n", targetPath)
 	}
 
-	err = gofsutil.FormatAndMount(ctx, fmt.Sprintf("/dev/%s", img.Device), targetPath, "ext4")
+	err = gofsutil.BindMount(ctx, source, targetPath, opts...)
 	if err != nil {
 		return nil, err
 	}
+	log.Printf("successfully published volume %s at %s", volumeID, targetPath)
 
 	return &csi.NodePublishVolumeResponse{}, nil
 }
@@ -156,59 +443,245 @@ func (ns *nodeServer) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpu
 	log.Printf("- volumeId: %s", volumeId)
 	log.Printf("  targetPath: %s", targetPath)
 
-	// unmounting  the volume should be here
-	err := gofsutil.Unmount(ctx, req.TargetPath)
+	if volumeId == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume ID missing in request")
+	}
+	if targetPath == "" {
+		return nil, status.Error(codes.InvalidArgument, "target path missing in request")
+	}
+
+	mount, err := findMount(ctx, targetPath)
 	if err != nil {
 		return nil, err
 	}
+	if mount != nil {
+		err = gofsutil.Unmount(ctx, targetPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// removes the mountpoint directory of a filesystem volume as well as the target file of a block volume
+	err = os.Remove(targetPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove the target path: %w", err)
+	}
+	log.Printf("successfully unpublished volume %s from %s", volumeId, targetPath)
 
-	// detach volume from this node
-	config, err := rest.InClusterConfig()
+	return &csi.NodeUnpublishVolumeResponse{}, nil
+}
+
+func (ns *nodeServer) NodeGetCapabilities(_ context.Context, _ *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
+	log.Print("NodeGetCapabilities called")
+	caps := []*csi.NodeServiceCapability{
+		{
+			Type: &csi.NodeServiceCapability_Rpc{
+				Rpc: &csi.NodeServiceCapability_RPC{
+					Type: csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME,
+				},
+			},
+		},
+		{
+			Type: &csi.NodeServiceCapability_Rpc{
+				Rpc: &csi.NodeServiceCapability_RPC{
+					Type: csi.NodeServiceCapability_RPC_EXPAND_VOLUME,
+				},
+			},
+		},
+		{
+			Type: &csi.NodeServiceCapability_Rpc{
+				Rpc: &csi.NodeServiceCapability_RPC{
+					Type: csi.NodeServiceCapability_RPC_GET_VOLUME_STATS,
+				},
+			},
+		},
+		{
+			Type: &csi.NodeServiceCapability_Rpc{
+				Rpc: &csi.NodeServiceCapability_RPC{
+					Type: csi.NodeServiceCapability_RPC_VOLUME_CONDITION,
+				},
+			},
+		},
+	}
+
+	return &csi.NodeGetCapabilitiesResponse{
+		Capabilities: caps,
+	}, nil
+}
+
+func (ns *nodeServer) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandVolumeRequest) (*csi.NodeExpandVolumeResponse, error) {
+	log.Print("NodeExpandVolume called")
+	volumeId := req.VolumeId
+	volumePath := req.VolumePath
+	log.Printf("- volumeId: %s", volumeId)
+	log.Printf("  volumePath: %s", volumePath)
+
+	if volumeId == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume ID missing in request")
+	}
+	if volumePath == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume path missing in request")
+	}
+
+	if req.GetVolumeCapability().GetBlock() != nil {
+		// the consumer of a block volume sees the new size of the device right away
+		log.Printf("volume %s is a block volume, no filesystem to expand", volumeId)
+		return &csi.NodeExpandVolumeResponse{
+			CapacityBytes: req.GetCapacityRange().GetRequiredBytes(),
+		}, nil
+	}
+
+	mount, err := findMount(ctx, volumePath)
 	if err != nil {
 		return nil, err
 	}
-	clientset, err := kubernetes.NewForConfig(config)
+	if mount == nil {
+		return nil, status.Errorf(codes.NotFound, "volume path %s is not mounted", volumePath)
+	}
+
+	// make the guest kernel pick up the new size of the disk
+	rescanPath := fmt.Sprintf("/sys/class/block/%s/device/rescan", filepath.Base(mount.Device))
+	err = os.WriteFile(rescanPath, []byte("1"), 0200)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to rescan the device %s: %w", mount.Device, err)
 	}
 
-	nodeObj, err := clientset.CoreV1().Nodes().Get(context.TODO(), os.Getenv("NODE_ID"), metav1.GetOptions{})
+	var cmd *exec.Cmd
+	switch mount.Type {
+	case "ext3", "ext4":
+		cmd = exec.Command("resize2fs", mount.Device)
+	case "xfs":
+		cmd = exec.Command("xfs_growfs", volumePath)
+	case "btrfs":
+		cmd = exec.Command("btrfs", "filesystem", "resize", "max", volumePath)
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "filesystem %s can't be expanded", mount.Type)
+	}
+	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to expand the filesystem on %s: %w (%s)", mount.Device, err, output)
 	}
-	kvmDomain := nodeObj.Labels["example.clew.cz/kvm-domain"]
-	log.Printf("  kvmNode: %s", kvmDomain)
+	log.Printf("successfully expanded the %s filesystem on %s", mount.Type, mount.Device)
 
-	conn, err := grpc.NewClient(os.Getenv("STORAGEAGENT_TARGET"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	return &csi.NodeExpandVolumeResponse{
+		CapacityBytes: req.GetCapacityRange().GetRequiredBytes(),
+	}, nil
+}
+
+func (ns *nodeServer) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVolumeStatsRequest) (*csi.NodeGetVolumeStatsResponse, error) {
+	log.Print("NodeGetVolumeStats called")
+	volumeId := req.VolumeId
+	volumePath := req.VolumePath
+	log.Printf("- volumeId: %s", volumeId)
+	log.Printf("  volumePath: %s", volumePath)
+
+	if volumeId == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume ID missing in request")
+	}
+	if volumePath == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume path missing in request")
+	}
+
+	fileInfo, err := os.Stat(volumePath)
+	if os.IsNotExist(err) {
+		return nil, status.Errorf(codes.NotFound, "volume path %s does not exist", volumePath)
+	}
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close()
 
-	c := sa.NewStorageAgentClient(conn)
+	if !fileInfo.IsDir() {
+		// a block volume is published as a file the device node is bind-mounted to
+		device, err := os.Open(volumePath)
+		if err != nil {
+			return nil, err
+		}
+		defer device.Close()
+		size, err := device.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the size of the block device: %w", err)
+		}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
+		return &csi.NodeGetVolumeStatsResponse{
+			Usage: []*csi.VolumeUsage{
+				{
+					Total: size,
+					Unit:  csi.VolumeUsage_BYTES,
+				},
+			},
+		}, nil
+	}
 
-	img, err := c.DetachVolume(ctx, &sa.VolumeRequest{
-		ImageId:    volumeId,
-		TargetPath: targetPath,
-		DomainName: kvmDomain,
-	})
+	var stat syscall.Statfs_t
+	err = syscall.Statfs(volumePath, &stat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the filesystem statistics: %w", err)
+	}
+
+	condition, err := getFilesystemCondition(ctx, volumePath, req.StagingTargetPath)
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("successfully detached volume %s to %s:%s", img.ImageId, kvmDomain, targetPath)
 
-	return &csi.NodeUnpublishVolumeResponse{}, nil
+	return &csi.NodeGetVolumeStatsResponse{
+		Usage: []*csi.VolumeUsage{
+			{
+				Available: int64(stat.Bavail) * stat.Bsize,
+				Total:     int64(stat.Blocks) * stat.Bsize,
+				Used:      int64(stat.Blocks-stat.Bfree) * stat.Bsize,
+				Unit:      csi.VolumeUsage_BYTES,
+			},
+			{
+				Available: int64(stat.Ffree),
+				Total:     int64(stat.Files),
+				Used:      int64(stat.Files - stat.Ffree),
+				Unit:      csi.VolumeUsage_INODES,
+			},
+		},
+		VolumeCondition: condition,
+	}, nil
 }
 
-func (ns *nodeServer) NodeGetCapabilities(_ context.Context, _ *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
-	log.Print("NodeGetCapabilities called")
-	caps := []*csi.NodeServiceCapability{}
+// getFilesystemCondition flags filesystems the kernel remounted read-only after I/O errors. The staged
+// mount is checked when known, as a pod may have got the volume published read-only on purpose
+func getFilesystemCondition(ctx context.Context, volumePath string, stagingTargetPath string) (*csi.VolumeCondition, error) {
+	mountPath := volumePath
+	if stagingTargetPath != "" {
+		mountPath = stagingTargetPath
+	}
 
-	return &csi.NodeGetCapabilitiesResponse{
-		Capabilities: caps,
+	mounts, err := gofsutil.GetMounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index := slices.IndexFunc(mounts, func(mount gofsutil.Info) bool { return mount.Path == mountPath })
+	if index < 0 {
+		return &csi.VolumeCondition{
+			Abnormal: true,
+			Message:  fmt.Sprintf("%s is not mounted", mountPath),
+		}, nil
+	}
+	mount := mounts[index]
+
+	// the kernel makes the filesystem read-only without changing the options of the mount, which stay rw,
+	// while a mount made ro on request, e.g. of a read-only volume, is fine
+	if !slices.Contains(mount.Opts, "ro") {
+		// statfs reports the flags of the filesystem along with the ones of the mount
+		var stat unix.Statfs_t
+		err = unix.Statfs(mountPath, &stat)
+		if err != nil {
+			return nil, err
+		}
+		if stat.Flags&unix.ST_RDONLY != 0 {
+			return &csi.VolumeCondition{
+				Abnormal: true,
+				Message:  fmt.Sprintf("filesystem on %s has been made read-only, probably after I/O errors", mount.Device),
+			}, nil
+		}
+	}
+
+	return &csi.VolumeCondition{
+		Abnormal: false,
+		Message:  "volume is healthy",
 	}, nil
 }
 
@@ -225,10 +698,64 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 	topologies := []*csi.Topology{}
 
 	log.Printf("- name: %s", req.Name)
-	log.Printf("  required capacity: %d", req.CapacityRange.RequiredBytes)
+	log.Printf("  required capacity: %d", req.GetCapacityRange().GetRequiredBytes())
 	log.Printf("  parameters: %v", req.GetParameters())
+	log.Printf("  mutable parameters: %v", req.GetMutableParameters())
+
+	volumeId := req.Name
+	if volumeId == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume name missing in request")
+	}
+
+	if message := checkVolumeCapabilities(req.VolumeCapabilities); message != "" {
+		return nil, status.Error(codes.InvalidArgument, message)
+	}
+	err := checkMutableParameters(req.GetMutableParameters())
+	if err != nil {
+		return nil, err
+	}
+	// the VolumeAttributesClass the PVC is created with overrides the disk tunables of the StorageClass
+	parameters := make(map[string]string)
+	maps.Copy(parameters, req.GetParameters())
+	maps.Copy(parameters, req.GetMutableParameters())
+
+	// the disk tunables are saved with the image and travel to ControllerPublishVolume in the volume context
+	ioTune, err := parseIoTune(parameters)
+	if err != nil {
+		return nil, err
+	}
+	cacheMode := parameters[CacheModeParameter]
+	if cacheMode != "" && !supportedCacheModes[cacheMode] {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported %s parameter: %s", CacheModeParameter, cacheMode)
+	}
+	imageOptions, err := parseImageOptions(parameters)
+	if err != nil {
+		return nil, err
+	}
 
-	volumeId := req.GetParameters()["csi.storage.k8s.io/pv/name"]
+	size := req.GetCapacityRange().GetRequiredBytes()
+	var snapshotId, sourceVolumeId string
+	if contentSource := req.GetVolumeContentSource(); contentSource != nil {
+		switch {
+		case contentSource.GetSnapshot() != nil:
+			snapshotId = contentSource.GetSnapshot().GetSnapshotId()
+			log.Printf("  source snapshot: %s", snapshotId)
+		case contentSource.GetVolume() != nil:
+			sourceVolumeId = contentSource.GetVolume().GetVolumeId()
+			log.Printf("  source volume: %s", sourceVolumeId)
+		default:
+			return nil, status.Error(codes.InvalidArgument, "unsupported volume content source")
+		}
+	}
+
+	linkedClone := false
+	switch cloneType := parameters[CloneTypeParameter]; cloneType {
+	case "", "full":
+	case "linked":
+		linkedClone = true
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported %s parameter: %s", CloneTypeParameter, cloneType)
+	}
 
 	conn, err := grpc.NewClient(os.Getenv("STORAGEAGENT_TARGET"), grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
@@ -241,10 +768,27 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 	// ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	// defer cancel()
 
-	img, err := c.CreateImage(ctx, &sa.ImageRequest{
-		ImageId: volumeId,
-		Size:    req.CapacityRange.RequiredBytes,
-	})
+	var img *sa.Image
+	if sourceVolumeId != "" {
+		img, err = c.CloneImage(ctx, &sa.CloneRequest{
+			ImageId:       volumeId,
+			SourceImageId: sourceVolumeId,
+			Size:          size,
+			Linked:        linkedClone,
+			Options:       imageOptions,
+			IoTune:        ioTune,
+			CacheMode:     cacheMode,
+		})
+	} else {
+		img, err = c.CreateImage(ctx, &sa.ImageRequest{
+			ImageId:    volumeId,
+			Size:       size,
+			SnapshotId: snapshotId,
+			Options:    imageOptions,
+			IoTune:     ioTune,
+			CacheMode:  cacheMode,
+		})
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -252,8 +796,8 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 	return &csi.CreateVolumeResponse{
 		Volume: &csi.Volume{
 			VolumeId:           img.ImageId,
-			CapacityBytes:      req.CapacityRange.RequiredBytes,
-			VolumeContext:      req.GetParameters(),
+			CapacityBytes:      img.Size,
+			VolumeContext:      parameters,
 			ContentSource:      req.GetVolumeContentSource(),
 			AccessibleTopology: topologies,
 		},
@@ -286,31 +830,324 @@ func (cs *controllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVol
 	return &csi.DeleteVolumeResponse{}, nil
 }
 
-func (cs *controllerServer) ControllerPublishVolume(_ context.Context, _ *csi.ControllerPublishVolumeRequest) (*csi.ControllerPublishVolumeResponse, error) {
-	log.Print("ControllerPublishVolume called")
-	return &csi.ControllerPublishVolumeResponse{
-		PublishContext: map[string]string{},
-	}, nil
-}
+// getKvmDomain returns the name of the KVM domain running the node, taken from the node label
+func getKvmDomain(ctx context.Context, nodeID string) (string, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return "", err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return "", err
+	}
+
+	nodeObj, err := clientset.CoreV1().Nodes().Get(ctx, nodeID, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return "", status.Errorf(codes.NotFound, "node %s does not exist", nodeID)
+	}
+	if err != nil {
+		return "", err
+	}
+	kvmDomain := nodeObj.Labels[KvmDomainLabel]
+	if kvmDomain == "" {
+		return "", status.Errorf(codes.NotFound, "node %s has no %s label", nodeID, KvmDomainLabel)
+	}
+	log.Printf("  kvmNode: %s", kvmDomain)
+	return kvmDomain, nil
+}
+
+// getKvmNodes maps the names of the KVM domains to the nodes they run
+func getKvmNodes(ctx context.Context) (map[string]string, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeList, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{
+		LabelSelector: KvmDomainLabel,
+	})
+	if err != nil {
+		return nil, err
+	}
+	nodes := make(map[string]string)
+	for _, node := range nodeList.Items {
+		nodes[node.Labels[KvmDomainLabel]] = node.Name
+	}
+	return nodes, nil
+}
+
+// publishedNodeIds returns the nodes run by the given KVM domains
+func publishedNodeIds(nodes map[string]string, domainNames []string) []string {
+	nodeIds := []string{}
+	for _, domainName := range domainNames {
+		if nodeID, ok := nodes[domainName]; ok {
+			nodeIds = append(nodeIds, nodeID)
+		}
+	}
+	return nodeIds
+}
+
+func (cs *controllerServer) ControllerPublishVolume(ctx context.Context, req *csi.ControllerPublishVolumeRequest) (*csi.ControllerPublishVolumeResponse, error) {
+	log.Print("ControllerPublishVolume called")
+	volumeID := req.VolumeId
+	nodeID := req.NodeId
+	log.Printf("- volumeId: %s", volumeID)
+	log.Printf("  nodeId: %s", nodeID)
+
+	if volumeID == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume ID missing in request")
+	}
+	if nodeID == "" {
+		return nil, status.Error(codes.InvalidArgument, "node ID missing in request")
+	}
+	if req.VolumeCapability == nil {
+		return nil, status.Error(codes.InvalidArgument, "volume capability missing in request")
+	}
+
+	// attach volume to the node
+	kvmDomain, err := getKvmDomain(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	ioTune, err := parseIoTune(req.VolumeContext)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := grpc.NewClient(os.Getenv("STORAGEAGENT_TARGET"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	c := sa.NewStorageAgentClient(conn)
+
+	img, err := c.AttachVolume(ctx, &sa.VolumeRequest{
+		ImageId:    volumeID,
+		DomainName: kvmDomain,
+		IoTune:     ioTune,
+		CacheMode:  req.VolumeContext[CacheModeParameter],
+		Readonly:   req.Readonly,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("successfully attached volume %s to %s as %s (readonly: %t)", img.ImageId, kvmDomain, img.Device, req.Readonly)
+
+	publishContext := map[string]string{
+		PublishContextDevice: img.Device,
+		PublishContextSerial: img.Serial,
+	}
+	if req.Readonly {
+		publishContext[PublishContextReadonly] = "true"
+	}
+	return &csi.ControllerPublishVolumeResponse{
+		PublishContext: publishContext,
+	}, nil
+}
+
+func (cs *controllerServer) ControllerUnpublishVolume(ctx context.Context, req *csi.ControllerUnpublishVolumeRequest) (*csi.ControllerUnpublishVolumeResponse, error) {
+	log.Print("ControllerUnpublishVolume called")
+	volumeID := req.VolumeId
+	nodeID := req.NodeId
+	log.Printf("- volumeId: %s", volumeID)
+	log.Printf("  nodeId: %s", nodeID)
+
+	if volumeID == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume ID missing in request")
+	}
+
+	// without a node ID the volume gets detached from whichever domain it is attached to,
+	// the same goes for a node which is gone already as the volume can be attached to a single node only
+	kvmDomain := ""
+	if nodeID != "" {
+		var err error
+		kvmDomain, err = getKvmDomain(ctx, nodeID)
+		if status.Code(err) == codes.NotFound {
+			log.Printf("node %s not found, detaching volume %s from any domain", nodeID, volumeID)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	conn, err := grpc.NewClient(os.Getenv("STORAGEAGENT_TARGET"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	c := sa.NewStorageAgentClient(conn)
+
+	img, err := c.DetachVolume(ctx, &sa.VolumeRequest{
+		ImageId:    volumeID,
+		DomainName: kvmDomain,
+	})
+	if status.Code(err) == codes.NotFound {
+		// a volume or a domain which does not exist can't have anything attached
+		log.Printf("volume %s or domain %s not found, nothing to detach", volumeID, kvmDomain)
+		return &csi.ControllerUnpublishVolumeResponse{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("successfully detached volume %s from %s", img.ImageId, kvmDomain)
 
-func (cs *controllerServer) ControllerUnpublishVolume(_ context.Context, _ *csi.ControllerUnpublishVolumeRequest) (*csi.ControllerUnpublishVolumeResponse, error) {
-	log.Print("ControllerUnpublishVolume called")
 	return &csi.ControllerUnpublishVolumeResponse{}, nil
 }
 
-func (cs *controllerServer) ValidateVolumeCapabilities(_ context.Context, _ *csi.ValidateVolumeCapabilitiesRequest) (*csi.ValidateVolumeCapabilitiesResponse, error) {
-	//TODO implement me
-	panic(ImplementMe)
+// supportedAccessModes are the access modes of a disk attached to a single KVM domain
+var supportedAccessModes = map[csi.VolumeCapability_AccessMode_Mode]bool{
+	csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER:        true,
+	csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY:   true,
+	csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER: true,
+	csi.VolumeCapability_AccessMode_SINGLE_NODE_MULTI_WRITER:  true,
 }
 
-func (cs *controllerServer) ListVolumes(_ context.Context, _ *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
-	//TODO implement me
-	panic(ImplementMe)
+// checkVolumeCapabilities returns a message explaining the first unsupported capability, or an empty string
+func checkVolumeCapabilities(capabilities []*csi.VolumeCapability) string {
+	for _, capability := range capabilities {
+		if capability.GetAccessMode() == nil {
+			return "access mode missing in the volume capability"
+		}
+		mode := capability.GetAccessMode().GetMode()
+		if !supportedAccessModes[mode] {
+			return fmt.Sprintf("access mode %s is not supported", mode)
+		}
+
+		switch {
+		case capability.GetBlock() != nil:
+		case capability.GetMount() != nil:
+			fsType := capability.GetMount().GetFsType()
+			if fsType != "" && !slices.Contains(supportedFsTypes, fsType) {
+				return fmt.Sprintf("filesystem type %s is not supported", fsType)
+			}
+		default:
+			return "access type missing in the volume capability"
+		}
+	}
+	return ""
 }
 
-func (cs *controllerServer) GetCapacity(_ context.Context, _ *csi.GetCapacityRequest) (*csi.GetCapacityResponse, error) {
-	//TODO implement me
-	panic(ImplementMe)
+func (cs *controllerServer) ValidateVolumeCapabilities(ctx context.Context, req *csi.ValidateVolumeCapabilitiesRequest) (*csi.ValidateVolumeCapabilitiesResponse, error) {
+	log.Print("ValidateVolumeCapabilities called")
+	volumeId := req.VolumeId
+	log.Printf("- volumeId: %s", volumeId)
+
+	if volumeId == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume ID missing in request")
+	}
+	if len(req.VolumeCapabilities) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "volume capabilities missing in request")
+	}
+
+	conn, err := grpc.NewClient(os.Getenv("STORAGEAGENT_TARGET"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	c := sa.NewStorageAgentClient(conn)
+
+	_, err = c.GetImage(ctx, &sa.ImageRequest{
+		ImageId: volumeId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if message := checkVolumeCapabilities(req.VolumeCapabilities); message != "" {
+		log.Printf("unsupported volume capabilities: %s", message)
+		return &csi.ValidateVolumeCapabilitiesResponse{
+			Message: message,
+		}, nil
+	}
+
+	return &csi.ValidateVolumeCapabilitiesResponse{
+		Confirmed: &csi.ValidateVolumeCapabilitiesResponse_Confirmed{
+			VolumeContext:      req.VolumeContext,
+			VolumeCapabilities: req.VolumeCapabilities,
+			Parameters:         req.Parameters,
+		},
+	}, nil
+}
+
+func (cs *controllerServer) ListVolumes(ctx context.Context, req *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
+	log.Print("ListVolumes called")
+
+	conn, err := grpc.NewClient(os.Getenv("STORAGEAGENT_TARGET"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	c := sa.NewStorageAgentClient(conn)
+
+	list, err := c.ListImages(ctx, &sa.ListImagesRequest{
+		MaxEntries:    req.MaxEntries,
+		StartingToken: req.StartingToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := getKvmNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*csi.ListVolumesResponse_Entry{}
+	for _, img := range list.Images {
+		entries = append(entries, &csi.ListVolumesResponse_Entry{
+			Volume: &csi.Volume{
+				VolumeId:      img.ImageId,
+				CapacityBytes: img.Size,
+			},
+			Status: &csi.ListVolumesResponse_VolumeStatus{
+				PublishedNodeIds: publishedNodeIds(nodes, img.DomainNames),
+				VolumeCondition: &csi.VolumeCondition{
+					Abnormal: img.Abnormal,
+					Message:  img.ConditionMessage,
+				},
+			},
+		})
+	}
+
+	return &csi.ListVolumesResponse{
+		Entries:   entries,
+		NextToken: list.NextToken,
+	}, nil
+}
+
+func (cs *controllerServer) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (*csi.GetCapacityResponse, error) {
+	log.Print("GetCapacity called")
+	log.Printf("- accessible topology: %v", req.GetAccessibleTopology().GetSegments())
+
+	if message := checkVolumeCapabilities(req.VolumeCapabilities); message != "" {
+		return &csi.GetCapacityResponse{}, nil
+	}
+
+	// all the volumes live on the single KVM host, so every topology segment shares its capacity
+	conn, err := grpc.NewClient(os.Getenv("STORAGEAGENT_TARGET"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	c := sa.NewStorageAgentClient(conn)
+
+	capacity, err := c.GetCapacity(ctx, &sa.CapacityRequest{})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("available capacity: %d of %d bytes", capacity.AvailableBytes, capacity.TotalBytes)
+
+	return &csi.GetCapacityResponse{
+		AvailableCapacity: capacity.AvailableBytes,
+	}, nil
 }
 
 func (cs *controllerServer) ControllerGetCapabilities(_ context.Context, _ *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
@@ -323,40 +1160,419 @@ func (cs *controllerServer) ControllerGetCapabilities(_ context.Context, _ *csi.
 			},
 		},
 	})
+	csc = append(csc, &csi.ControllerServiceCapability{
+		Type: &csi.ControllerServiceCapability_Rpc{
+			Rpc: &csi.ControllerServiceCapability_RPC{
+				Type: csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME,
+			},
+		},
+	})
+	csc = append(csc, &csi.ControllerServiceCapability{
+		Type: &csi.ControllerServiceCapability_Rpc{
+			Rpc: &csi.ControllerServiceCapability_RPC{
+				Type: csi.ControllerServiceCapability_RPC_LIST_VOLUMES,
+			},
+		},
+	})
+	csc = append(csc, &csi.ControllerServiceCapability{
+		Type: &csi.ControllerServiceCapability_Rpc{
+			Rpc: &csi.ControllerServiceCapability_RPC{
+				Type: csi.ControllerServiceCapability_RPC_LIST_VOLUMES_PUBLISHED_NODES,
+			},
+		},
+	})
+	csc = append(csc, &csi.ControllerServiceCapability{
+		Type: &csi.ControllerServiceCapability_Rpc{
+			Rpc: &csi.ControllerServiceCapability_RPC{
+				Type: csi.ControllerServiceCapability_RPC_GET_CAPACITY,
+			},
+		},
+	})
+	csc = append(csc, &csi.ControllerServiceCapability{
+		Type: &csi.ControllerServiceCapability_Rpc{
+			Rpc: &csi.ControllerServiceCapability_RPC{
+				Type: csi.ControllerServiceCapability_RPC_GET_VOLUME,
+			},
+		},
+	})
+	csc = append(csc, &csi.ControllerServiceCapability{
+		Type: &csi.ControllerServiceCapability_Rpc{
+			Rpc: &csi.ControllerServiceCapability_RPC{
+				Type: csi.ControllerServiceCapability_RPC_VOLUME_CONDITION,
+			},
+		},
+	})
+	csc = append(csc, &csi.ControllerServiceCapability{
+		Type: &csi.ControllerServiceCapability_Rpc{
+			Rpc: &csi.ControllerServiceCapability_RPC{
+				Type: csi.ControllerServiceCapability_RPC_MODIFY_VOLUME,
+			},
+		},
+	})
+	csc = append(csc, &csi.ControllerServiceCapability{
+		Type: &csi.ControllerServiceCapability_Rpc{
+			Rpc: &csi.ControllerServiceCapability_RPC{
+				Type: csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
+			},
+		},
+	})
+	csc = append(csc, &csi.ControllerServiceCapability{
+		Type: &csi.ControllerServiceCapability_Rpc{
+			Rpc: &csi.ControllerServiceCapability_RPC{
+				Type: csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS,
+			},
+		},
+	})
+	csc = append(csc, &csi.ControllerServiceCapability{
+		Type: &csi.ControllerServiceCapability_Rpc{
+			Rpc: &csi.ControllerServiceCapability_RPC{
+				Type: csi.ControllerServiceCapability_RPC_CLONE_VOLUME,
+			},
+		},
+	})
+	csc = append(csc, &csi.ControllerServiceCapability{
+		Type: &csi.ControllerServiceCapability_Rpc{
+			Rpc: &csi.ControllerServiceCapability_RPC{
+				Type: csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
+			},
+		},
+	})
 
 	return &csi.ControllerGetCapabilitiesResponse{
 		Capabilities: csc,
 	}, nil
 }
 
-func (cs *controllerServer) CreateSnapshot(_ context.Context, _ *csi.CreateSnapshotRequest) (*csi.CreateSnapshotResponse, error) {
-	//TODO implement me
-	panic(ImplementMe)
+func snapshotFromAgent(snapshot *sa.Snapshot) *csi.Snapshot {
+	return &csi.Snapshot{
+		SnapshotId:     snapshot.SnapshotId,
+		SourceVolumeId: snapshot.ImageId,
+		SizeBytes:      snapshot.Size,
+		CreationTime:   timestamppb.New(time.Unix(snapshot.CreationTime, 0)),
+		ReadyToUse:     snapshot.ReadyToUse,
+	}
 }
 
-func (cs *controllerServer) DeleteSnapshot(_ context.Context, _ *csi.DeleteSnapshotRequest) (*csi.DeleteSnapshotResponse, error) {
-	//TODO implement me
-	panic(ImplementMe)
+func (cs *controllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshotRequest) (*csi.CreateSnapshotResponse, error) {
+	log.Print("CreateSnapshot called")
+	log.Printf("- name: %s", req.Name)
+	log.Printf("  source volume: %s", req.SourceVolumeId)
+
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "snapshot name missing in request")
+	}
+	if req.SourceVolumeId == "" {
+		return nil, status.Error(codes.InvalidArgument, "source volume ID missing in request")
+	}
+
+	conn, err := grpc.NewClient(os.Getenv("STORAGEAGENT_TARGET"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	c := sa.NewStorageAgentClient(conn)
+
+	snapshot, err := c.CreateSnapshot(ctx, &sa.SnapshotRequest{
+		SnapshotId: req.Name,
+		ImageId:    req.SourceVolumeId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("successfully created snapshot %s of the volume %s", snapshot.SnapshotId, snapshot.ImageId)
+
+	return &csi.CreateSnapshotResponse{
+		Snapshot: snapshotFromAgent(snapshot),
+	}, nil
 }
 
-func (cs *controllerServer) ListSnapshots(_ context.Context, _ *csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
-	//TODO implement me
-	panic(ImplementMe)
+func (cs *controllerServer) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshotRequest) (*csi.DeleteSnapshotResponse, error) {
+	log.Print("DeleteSnapshot called")
+	log.Printf("- snapshotId: %s", req.SnapshotId)
+
+	if req.SnapshotId == "" {
+		return nil, status.Error(codes.InvalidArgument, "snapshot ID missing in request")
+	}
+
+	conn, err := grpc.NewClient(os.Getenv("STORAGEAGENT_TARGET"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	c := sa.NewStorageAgentClient(conn)
+
+	_, err = c.DeleteSnapshot(ctx, &sa.SnapshotRequest{
+		SnapshotId: req.SnapshotId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &csi.DeleteSnapshotResponse{}, nil
 }
 
-func (cs *controllerServer) ControllerExpandVolume(_ context.Context, _ *csi.ControllerExpandVolumeRequest) (*csi.ControllerExpandVolumeResponse, error) {
-	//TODO implement me
-	panic(ImplementMe)
+func (cs *controllerServer) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
+	log.Print("ListSnapshots called")
+
+	conn, err := grpc.NewClient(os.Getenv("STORAGEAGENT_TARGET"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	c := sa.NewStorageAgentClient(conn)
+
+	list, err := c.ListSnapshots(ctx, &sa.ListSnapshotsRequest{
+		SnapshotId:    req.SnapshotId,
+		ImageId:       req.SourceVolumeId,
+		MaxEntries:    req.MaxEntries,
+		StartingToken: req.StartingToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*csi.ListSnapshotsResponse_Entry{}
+	for _, snapshot := range list.Snapshots {
+		entries = append(entries, &csi.ListSnapshotsResponse_Entry{
+			Snapshot: snapshotFromAgent(snapshot),
+		})
+	}
+
+	return &csi.ListSnapshotsResponse{
+		Entries:   entries,
+		NextToken: list.NextToken,
+	}, nil
 }
 
-func (cs *controllerServer) ControllerGetVolume(_ context.Context, _ *csi.ControllerGetVolumeRequest) (*csi.ControllerGetVolumeResponse, error) {
-	//TODO implement me
-	panic(ImplementMe)
+func (cs *controllerServer) ControllerExpandVolume(ctx context.Context, req *csi.ControllerExpandVolumeRequest) (*csi.ControllerExpandVolumeResponse, error) {
+	log.Print("ControllerExpandVolume called")
+	volumeId := req.VolumeId
+	log.Printf("- volumeId: %s", volumeId)
+	log.Printf("  required capacity: %d", req.GetCapacityRange().GetRequiredBytes())
+
+	if volumeId == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume ID missing in request")
+	}
+	if req.GetCapacityRange() == nil {
+		return nil, status.Error(codes.InvalidArgument, "capacity range missing in request")
+	}
+
+	conn, err := grpc.NewClient(os.Getenv("STORAGEAGENT_TARGET"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	c := sa.NewStorageAgentClient(conn)
+
+	img, err := c.ResizeImage(ctx, &sa.ResizeRequest{
+		ImageId: volumeId,
+		Size:    req.GetCapacityRange().GetRequiredBytes(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("successfully expanded volume %s to %d bytes", img.ImageId, img.Size)
+
+	return &csi.ControllerExpandVolumeResponse{
+		CapacityBytes: img.Size,
+		// the filesystem inside the guest has to be grown by the node server
+		NodeExpansionRequired: req.GetVolumeCapability().GetBlock() == nil,
+	}, nil
+}
+
+func (cs *controllerServer) ControllerGetVolume(ctx context.Context, req *csi.ControllerGetVolumeRequest) (*csi.ControllerGetVolumeResponse, error) {
+	log.Print("ControllerGetVolume called")
+	volumeId := req.VolumeId
+	log.Printf("- volumeId: %s", volumeId)
+
+	if volumeId == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume ID missing in request")
+	}
+
+	conn, err := grpc.NewClient(os.Getenv("STORAGEAGENT_TARGET"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	c := sa.NewStorageAgentClient(conn)
+
+	img, err := c.GetImage(ctx, &sa.ImageRequest{
+		ImageId:    volumeId,
+		WithStatus: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if img.Abnormal {
+		log.Printf("volume %s is abnormal: %s", volumeId, img.ConditionMessage)
+	}
+
+	nodes, err := getKvmNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &csi.ControllerGetVolumeResponse{
+		Volume: &csi.Volume{
+			VolumeId:      img.ImageId,
+			CapacityBytes: img.Size,
+		},
+		Status: &csi.ControllerGetVolumeResponse_VolumeStatus{
+			PublishedNodeIds: publishedNodeIds(nodes, img.DomainNames),
+			VolumeCondition: &csi.VolumeCondition{
+				Abnormal: img.Abnormal,
+				Message:  img.ConditionMessage,
+			},
+		},
+	}, nil
+}
+
+// checkMutableParameters fails for the parameters which a VolumeAttributesClass can't set
+func checkMutableParameters(parameters map[string]string) error {
+	for name := range parameters {
+		switch name {
+		case TotalBytesSecParameter, ReadBytesSecParameter, WriteBytesSecParameter,
+			TotalIopsSecParameter, ReadIopsSecParameter, WriteIopsSecParameter, CacheModeParameter:
+		default:
+			return status.Errorf(codes.InvalidArgument, "parameter %s can't be modified", name)
+		}
+	}
+	return nil
+}
+
+// supportedCacheModes are the libvirt disk cache modes
+var supportedCacheModes = map[string]bool{
+	"default":      true,
+	"none":         true,
+	"writethrough": true,
+	"writeback":    true,
+	"directsync":   true,
+	"unsafe":       true,
 }
 
-func (cs *controllerServer) ControllerModifyVolume(_ context.Context, _ *csi.ControllerModifyVolumeRequest) (*csi.ControllerModifyVolumeResponse, error) {
-	//TODO implement me
-	panic(ImplementMe)
+// parseIoTune reads the I/O limits from the parameters, the limits which are not set are unlimited
+func parseIoTune(parameters map[string]string) (*sa.IoTune, error) {
+	ioTune := &sa.IoTune{}
+	limits := map[string]*int64{
+		TotalBytesSecParameter: &ioTune.TotalBytesSec,
+		ReadBytesSecParameter:  &ioTune.ReadBytesSec,
+		WriteBytesSecParameter: &ioTune.WriteBytesSec,
+		TotalIopsSecParameter:  &ioTune.TotalIopsSec,
+		ReadIopsSecParameter:   &ioTune.ReadIopsSec,
+		WriteIopsSecParameter:  &ioTune.WriteIopsSec,
+	}
+	for name, limit := range limits {
+		value, ok := parameters[name]
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid %s parameter: %s", name, value)
+		}
+		*limit = parsed
+	}
+
+	// libvirt refuses a total limit combined with the read or write one
+	if ioTune.TotalBytesSec > 0 && (ioTune.ReadBytesSec > 0 || ioTune.WriteBytesSec > 0) {
+		return nil, status.Errorf(codes.InvalidArgument, "%s can't be combined with %s or %s", TotalBytesSecParameter, ReadBytesSecParameter, WriteBytesSecParameter)
+	}
+	if ioTune.TotalIopsSec > 0 && (ioTune.ReadIopsSec > 0 || ioTune.WriteIopsSec > 0) {
+		return nil, status.Errorf(codes.InvalidArgument, "%s can't be combined with %s or %s", TotalIopsSecParameter, ReadIopsSecParameter, WriteIopsSecParameter)
+	}
+	return ioTune, nil
+}
+
+// clusterSizePattern matches the cluster sizes the storage agent accepts, e.g. 65536, 64k or 2M
+var clusterSizePattern = regexp.MustCompile(`^[0-9]+[kKM]?$`)
+
+// parseImageOptions reads the format and the allocation of the image from the parameters
+func parseImageOptions(parameters map[string]string) (*sa.ImageOptions, error) {
+	options := &sa.ImageOptions{
+		Format:        parameters[FormatParameter],
+		Preallocation: parameters[PreallocationParameter],
+		ClusterSize:   parameters[ClusterSizeParameter],
+	}
+	if value, ok := parameters[LazyRefcountsParameter]; ok {
+		lazyRefcounts, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid %s parameter: %s", LazyRefcountsParameter, value)
+		}
+		options.LazyRefcounts = lazyRefcounts
+	}
+
+	switch options.Format {
+	case "", "qcow2":
+	case "raw":
+		// metadata preallocation, cluster size and refcounts exist in qcow2 images only
+		if options.Preallocation == "metadata" || options.ClusterSize != "" || options.LazyRefcounts {
+			return nil, status.Errorf(codes.InvalidArgument, "%s, %s and %s=metadata are supported by qcow2 images only",
+				ClusterSizeParameter, LazyRefcountsParameter, PreallocationParameter)
+		}
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported %s parameter: %s", FormatParameter, options.Format)
+	}
+	switch options.Preallocation {
+	case "", "off", "metadata", "falloc", "full":
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported %s parameter: %s", PreallocationParameter, options.Preallocation)
+	}
+	if options.ClusterSize != "" && !clusterSizePattern.MatchString(options.ClusterSize) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid %s parameter: %s", ClusterSizeParameter, options.ClusterSize)
+	}
+	return options, nil
+}
+
+func (cs *controllerServer) ControllerModifyVolume(ctx context.Context, req *csi.ControllerModifyVolumeRequest) (*csi.ControllerModifyVolumeResponse, error) {
+	log.Print("ControllerModifyVolume called")
+	volumeId := req.VolumeId
+	log.Printf("- volumeId: %s", volumeId)
+	log.Printf("  mutable parameters: %v", req.MutableParameters)
+
+	if volumeId == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume ID missing in request")
+	}
+
+	err := checkMutableParameters(req.MutableParameters)
+	if err != nil {
+		return nil, err
+	}
+	// only the parameters given are changed, the storage agent keeps the other settings of the volume
+	updateMask := slices.Sorted(maps.Keys(req.MutableParameters))
+	ioTune, err := parseIoTune(req.MutableParameters)
+	if err != nil {
+		return nil, err
+	}
+	cacheMode := req.MutableParameters[CacheModeParameter]
+	if cacheMode != "" && !supportedCacheModes[cacheMode] {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported %s parameter: %s", CacheModeParameter, cacheMode)
+	}
+
+	conn, err := grpc.NewClient(os.Getenv("STORAGEAGENT_TARGET"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	c := sa.NewStorageAgentClient(conn)
+
+	_, err = c.ModifyImage(ctx, &sa.ModifyRequest{
+		ImageId:    volumeId,
+		IoTune:     ioTune,
+		CacheMode:  cacheMode,
+		UpdateMask: updateMask,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("successfully modified volume %s", volumeId)
+
+	return &csi.ControllerModifyVolumeResponse{}, nil
 }
 
 func RunServer(runControllerServer bool, runNodeServer bool) {
@@ -0,0 +1,127 @@
+package kvm
+
+import (
+	"context"
+	"fmt"
+	"github.com/digitalocean/go-libvirt"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// keepaliveInterval is how often an idle connection gets checked
+const keepaliveInterval = 5 * time.Second
+
+// Connection is a libvirt connection shared by all the callers. It gets dialed again whenever
+// libvirtd goes away, e.g. when it is restarted during a package upgrade.
+type Connection struct {
+	uri   string
+	pool  string
+	slots chan struct{}
+	mutex sync.Mutex
+	l     *libvirt.Libvirt
+}
+
+// NewConnection prepares a connection to libvirt at the URI used by at most maxCalls callers at once,
+// the images are kept in the storage pool
+func NewConnection(uri string, pool string, maxCalls int) *Connection {
+	return &Connection{
+		uri:   uri,
+		pool:  pool,
+		slots: make(chan struct{}, maxCalls),
+	}
+}
+
+// Acquire waits for a free slot and returns a Kvm using the shared connection, the slot must be given back
+// by calling release. The Kvm must not be disconnected.
+func (c *Connection) Acquire(ctx context.Context) (*Kvm, func(), error) {
+	select {
+	case c.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, fmt.Errorf("%w: %w", ErrLibvirtUnavailable, ctx.Err())
+	}
+	release := func() { <-c.slots }
+
+	l, err := c.get()
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return &Kvm{URI: c.uri, Pool: c.pool, l: l}, release, nil
+}
+
+// get returns the connection, dialing libvirt again when the previous connection has been closed
+func (c *Connection) get() (*libvirt.Libvirt, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.l != nil && c.l.IsConnected() {
+		return c.l, nil
+	}
+
+	uri, err := url.Parse(c.uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid libvirt URI %s: %w", c.uri, err)
+	}
+	l, err := libvirt.ConnectToURI(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLibvirtUnavailable, err)
+	}
+	if c.l != nil {
+		log.Printf("reconnected to libvirt at %s", c.uri)
+	}
+	c.l = l
+	return l, nil
+}
+
+// Run keeps the connection alive until the context is done, then it closes the connection
+func (c *Connection) Run(ctx context.Context) {
+	ticker := time.NewTicker(keepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.close()
+			return
+		case <-ticker.C:
+			c.keepalive()
+		}
+	}
+}
+
+// keepalive calls libvirt to find out a connection which does not work anymore although its socket
+// is still open, such a connection is closed and dialed again
+func (c *Connection) keepalive() {
+	l, err := c.get()
+	if err != nil {
+		log.Printf("libvirt is unavailable: %v", err)
+		return
+	}
+	_, err = l.ConnectGetLibVersion()
+	if err == nil {
+		return
+	}
+
+	log.Printf("libvirt connection is broken, reconnecting: %v", err)
+	c.mutex.Lock()
+	if c.l == l {
+		_ = l.Disconnect()
+	}
+	c.mutex.Unlock()
+	_, err = c.get()
+	if err != nil {
+		log.Printf("libvirt is unavailable: %v", err)
+	}
+}
+
+func (c *Connection) close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.l != nil && c.l.IsConnected() {
+		_ = c.l.Disconnect()
+	}
+	c.l = nil
+}
@@ -1,22 +1,75 @@
 package kvm
 
 import (
+	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"github.com/digitalocean/go-libvirt"
 	"log"
-	"net/url"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
 )
 
+// Errors returned by Kvm, wrapped together with the details of the failure
+var (
+	ErrDomainNotFound     = errors.New("domain not found")
+	ErrDomainNotRunning   = errors.New("domain not running")
+	ErrImageNotFound      = errors.New("image not found")
+	ErrNoFreeDevice       = errors.New("no free device slot")
+	ErrLibvirtUnavailable = errors.New("libvirt unavailable")
+)
+
+// libvirtError wraps the error of a libvirt call into one of the errors above when it is possible
+func libvirtError(message string, err error) error {
+	var libvirtErr libvirt.Error
+	switch {
+	case !errors.As(err, &libvirtErr):
+		// anything but an error reported by libvirtd itself means the connection failed
+		return fmt.Errorf("%s: %w: %w", message, ErrLibvirtUnavailable, err)
+	case libvirtErr.Code == uint32(libvirt.ErrNoDomain):
+		return fmt.Errorf("%s: %w: %w", message, ErrDomainNotFound, err)
+	case libvirtErr.Code == uint32(libvirt.ErrOperationInvalid):
+		// libvirt refuses hot-plugging and block jobs on inactive domains this way
+		return fmt.Errorf("%s: %w: %w", message, ErrDomainNotRunning, err)
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
+
+// checkImageExists fails with ErrImageNotFound when the image file is missing
+func checkImageExists(filepath string) error {
+	if _, err := os.Stat(filepath); os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrImageNotFound, filepath)
+	}
+	return nil
+}
+
 type Domain struct {
 	Devices Devices `xml:"devices"`
 }
 
 type Devices struct {
-	Disks []Disk `xml:"disk"`
+	Disks       []Disk       `xml:"disk"`
+	Controllers []Controller `xml:"controller"`
+}
+
+// Controller represents a disk controller of the domain
+type Controller struct {
+	XMLName xml.Name `xml:"controller"`
+	Type    string   `xml:"type,attr"`
+	Index   int      `xml:"index,attr"`
+	Model   string   `xml:"model,attr,omitempty"`
+}
+
+// DriveAddress places a disk on a unit of a SCSI controller
+type DriveAddress struct {
+	Type       string `xml:"type,attr"`
+	Controller int    `xml:"controller,attr"`
+	Bus        int    `xml:"bus,attr"`
+	Target     int    `xml:"target,attr"`
+	Unit       int    `xml:"unit,attr"`
 }
 
 // Disk structure to represent a disk in the domain's XML
@@ -25,48 +78,108 @@ type Disk struct {
 	Type    string   `xml:"type,attr"`
 	Device  string   `xml:"device,attr"`
 	Driver  struct {
-		Name string `xml:"name,attr"`
-		Type string `xml:"type,attr"`
+		Name  string `xml:"name,attr"`
+		Type  string `xml:"type,attr"`
+		Cache string `xml:"cache,attr,omitempty"`
 	} `xml:"driver"`
 	Source struct {
-		File string `xml:"file,attr"`
+		File string `xml:"file,attr,omitempty"`
+		Dev  string `xml:"dev,attr,omitempty"`
 	} `xml:"source"`
 	Target struct {
 		Dev string `xml:"dev,attr"`
 		Bus string `xml:"bus,attr"`
 	} `xml:"target"`
+	ReadOnly *struct{}     `xml:"readonly,omitempty"`
+	Serial   string        `xml:"serial,omitempty"`
+	IoTune   *IoTune       `xml:"iotune,omitempty"`
+	Address  *DriveAddress `xml:"address,omitempty"`
 }
 
-type Kvm struct {
-	URI string
-	l   *libvirt.Libvirt
+// sourcePath returns the image file or the block device of the disk
+func (d Disk) sourcePath() string {
+	if d.Source.Dev != "" {
+		return d.Source.Dev
+	}
+	return d.Source.File
 }
 
-func (k *Kvm) Connect() error {
-	uri, _ := url.Parse(k.URI)
-	l, err := libvirt.ConnectToURI(uri)
-	if err != nil {
-		return err
+// IoTune holds the I/O limits of a disk, zero meaning unlimited
+type IoTune struct {
+	TotalBytesSec uint64 `xml:"total_bytes_sec,omitempty" json:"totalBytesSec,omitempty"`
+	ReadBytesSec  uint64 `xml:"read_bytes_sec,omitempty" json:"readBytesSec,omitempty"`
+	WriteBytesSec uint64 `xml:"write_bytes_sec,omitempty" json:"writeBytesSec,omitempty"`
+	TotalIopsSec  uint64 `xml:"total_iops_sec,omitempty" json:"totalIopsSec,omitempty"`
+	ReadIopsSec   uint64 `xml:"read_iops_sec,omitempty" json:"readIopsSec,omitempty"`
+	WriteIopsSec  uint64 `xml:"write_iops_sec,omitempty" json:"writeIopsSec,omitempty"`
+}
+
+// DiskSettings are the tunables of a volume applied whenever it gets attached
+type DiskSettings struct {
+	IoTune IoTune `json:"ioTune"`
+	Cache  string `json:"cache,omitempty"`
+	// Format is the format the image was created in, the disk is attached in it rather than in
+	// the one detected from the contents of the image, which the guest writes
+	Format string `json:"format,omitempty"`
+}
+
+// DefaultImageFormat is the format of the images created without asking for one
+const DefaultImageFormat = "qcow2"
+
+// RawFormat is the format of the images holding the data of the disk as they are
+const RawFormat = "raw"
+
+// maxSerialLength is the part of the disk serial QEMU puts into the SCSI device identification,
+// which the guest names /dev/disk/by-id/scsi-0QEMU_QEMU_HARDDISK_<serial> after
+const maxSerialLength = 20
+
+// DiskSerial returns the serial of the disk of an image, which is the image ID cut to its last
+// characters when it is too long
+func DiskSerial(imageID string) string {
+	if len(imageID) > maxSerialLength {
+		return imageID[len(imageID)-maxSerialLength:]
 	}
+	return imageID
+}
 
-	k.l = l
-	return nil
+// ImageOptions control the format and the allocation of a new image
+type ImageOptions struct {
+	Format        string
+	Preallocation string
+	ClusterSize   string
+	LazyRefcounts bool
+}
+
+func (o ImageOptions) format() string {
+	if o.Format == "" {
+		return DefaultImageFormat
+	}
+	return o.Format
+}
+
+// ImageCheck holds the result of `qemu-img check`
+type ImageCheck struct {
+	Corruptions int `json:"corruptions"`
+	Leaks       int `json:"leaks"`
+	CheckErrors int `json:"check-errors"`
 }
 
-func (k *Kvm) Disconnect() error {
-	return k.l.Disconnect()
+type Kvm struct {
+	URI  string
+	Pool string // the storage pool holding the images, DefaultPool when empty
+	l    *libvirt.Libvirt
 }
 
 func (k *Kvm) getDomainByName(domainName string) (libvirt.Domain, error) {
 	// Find the domain by name
 	dom, err := k.l.DomainLookupByName(domainName)
 	if err != nil {
-		return libvirt.Domain{}, fmt.Errorf("error looking up the domain by name: %w", err)
+		return libvirt.Domain{}, libvirtError("error looking up the domain by name", err)
 	}
 	// Get the domain XML
 	domXML, err := k.l.DomainGetXMLDesc(dom, 0)
 	if err != nil {
-		return libvirt.Domain{}, fmt.Errorf("error getting the domain XML: %w", err)
+		return libvirt.Domain{}, libvirtError("error getting the domain XML", err)
 	}
 	// Parse the XML
 	var domain libvirt.Domain
@@ -80,13 +193,23 @@ func (k *Kvm) getDomain(domainName string) (Domain, error) {
 	// Find the domain by name
 	domain, err := k.l.DomainLookupByName(domainName)
 	if err != nil {
-		return Domain{}, fmt.Errorf("error looking up the domain by name: %w", err)
+		return Domain{}, libvirtError("error looking up the domain by name", err)
 	}
 
+	return k.getDomainXML(domain, 0)
+}
+
+// getDomainConfig returns the persistent definition of the domain, which differs from the live one
+// while the domain runs
+func (k *Kvm) getDomainConfig(domain libvirt.Domain) (Domain, error) {
+	return k.getDomainXML(domain, libvirt.DomainXMLInactive)
+}
+
+func (k *Kvm) getDomainXML(domain libvirt.Domain, flags libvirt.DomainXMLFlags) (Domain, error) {
 	// Get the domain XML
-	domXML, err := k.l.DomainGetXMLDesc(domain, 0)
+	domXML, err := k.l.DomainGetXMLDesc(domain, flags)
 	if err != nil {
-		return Domain{}, fmt.Errorf("error getting the domain XML: %w", err)
+		return Domain{}, libvirtError("error getting the domain XML", err)
 	}
 	// Parse the XML
 	var dom Domain
@@ -98,61 +221,217 @@ func (k *Kvm) getDomain(domainName string) (Domain, error) {
 	return dom, nil
 }
 
-func (k *Kvm) GetDeviceNameBySource(domainName string, sourceFile string) (string, error) {
-	dom, err := k.getDomain(domainName)
-	if err != nil {
-		return "", err
-	}
+// virtioSCSITargets is the number of targets of a virtio-scsi controller, each disk gets a target
+// of its own, so that a controller is only added once all of them are taken
+const virtioSCSITargets = 256
 
-	for _, disk := range dom.Devices.Disks {
-		if disk.Source.File == sourceFile {
-			return disk.Target.Dev, nil
-		}
-	}
+// maxDevices limits the device names to sda..sdzz
+const maxDevices = 26 + 26*26
 
-	return "", fmt.Errorf("can't find device for the image %s", sourceFile)
+// deviceName returns the name of the SCSI disk with the given index: sda..sdz, sdaa..sdaz, ...
+func deviceName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('a'+index%26)) + name
+		index = index/26 - 1
+	}
+	return "sd" + name
 }
 
-func (k *Kvm) getNextAvailableDevice(dom Domain) string {
+// getNextAvailableDevice returns an unused device name and a free target of a virtio-scsi controller.
+// When all the virtio-scsi controllers are full, the address points to a controller which does not exist yet.
+func (k *Kvm) getNextAvailableDevice(dom Domain) (string, DriveAddress, error) {
 	// Track used device names like sda, sdb, etc.
 	usedDevices := make(map[string]bool)
+	usedUnits := make(map[[3]int]bool)
 	for _, disk := range dom.Devices.Disks {
 		if strings.HasPrefix(disk.Target.Dev, "sd") {
 			usedDevices[disk.Target.Dev] = true
 		}
+		if disk.Target.Bus == "scsi" && disk.Address != nil && disk.Address.Type == "drive" {
+			usedUnits[[3]int{disk.Address.Controller, disk.Address.Target, disk.Address.Unit}] = true
+		}
 	}
 
-	// Generate the next available device name (e.g., sda, sdb, sdc, ...)
-	for letter := 'a'; letter <= 'z'; letter++ {
-		devName := fmt.Sprintf("sd%c", letter)
-		if !usedDevices[devName] {
-			return devName
+	// Generate the next available device name (e.g., sda, sdb, ..., sdz, sdaa, ...)
+	devName := ""
+	for index := 0; index < maxDevices; index++ {
+		if !usedDevices[deviceName(index)] {
+			devName = deviceName(index)
+			break
+		}
+	}
+	if devName == "" {
+		return "", DriveAddress{}, ErrNoFreeDevice
+	}
+
+	nextController := 0
+	for _, controller := range dom.Devices.Controllers {
+		if controller.Type != "scsi" {
+			continue
+		}
+		nextController = max(nextController, controller.Index+1)
+		if controller.Model != "virtio-scsi" {
+			continue
+		}
+		for target := 0; target < virtioSCSITargets; target++ {
+			if !usedUnits[[3]int{controller.Index, target, 0}] {
+				return devName, DriveAddress{Type: "drive", Controller: controller.Index, Target: target}, nil
+			}
 		}
 	}
-	return ""
+	return devName, DriveAddress{Type: "drive", Controller: nextController}, nil
 }
 
-func (k *Kvm) FindNextUsableDeviceName(domainName string) (string, error) {
-	dom, err := k.getDomain(domainName)
+// deviceModifyFlags returns the flags changing the devices of the running domain as well as of its
+// persistent definition, so that the disks survive restarting the domain. A shut off domain gets
+// its definition changed only.
+func (k *Kvm) deviceModifyFlags(dom libvirt.Domain) (libvirt.DomainDeviceModifyFlags, error) {
+	active, err := k.l.DomainIsActive(dom)
 	if err != nil {
-		return "", err
+		return 0, libvirtError("error getting the state of the domain", err)
+	}
+	persistent, err := k.l.DomainIsPersistent(dom)
+	if err != nil {
+		return 0, libvirtError("error getting the state of the domain", err)
+	}
+
+	var flags libvirt.DomainDeviceModifyFlags
+	if active == 1 {
+		flags |= libvirt.DomainDeviceModifyLive
+	}
+	if persistent == 1 {
+		flags |= libvirt.DomainDeviceModifyConfig
+	}
+	return flags, nil
+}
+
+// diskModifyFlags returns the flags changing an existing disk of the domain, leaving out the persistent
+// definition when the disk was hot-plugged into the running domain only
+func (k *Kvm) diskModifyFlags(dom libvirt.Domain, targetDevice string) (libvirt.DomainDeviceModifyFlags, error) {
+	flags, err := k.deviceModifyFlags(dom)
+	if err != nil {
+		return 0, err
+	}
+	if flags == libvirt.DomainDeviceModifyLive|libvirt.DomainDeviceModifyConfig {
+		config, err := k.getDomainConfig(dom)
+		if err != nil {
+			return 0, err
+		}
+		if !slices.ContainsFunc(config.Devices.Disks, func(disk Disk) bool {
+			return disk.Target.Dev == targetDevice
+		}) {
+			flags = libvirt.DomainDeviceModifyLive
+		}
+	}
+	return flags, nil
+}
+
+// addController hot-adds a virtio-scsi controller with the given index to the domain
+func (k *Kvm) addController(dom libvirt.Domain, index int, flags libvirt.DomainDeviceModifyFlags) error {
+	controllerXML, err := xml.Marshal(Controller{
+		Type:  "scsi",
+		Index: index,
+		Model: "virtio-scsi",
+	})
+	if err != nil {
+		return err
+	}
+	err = k.l.DomainAttachDeviceFlags(dom, string(controllerXML), uint32(flags))
+	if err != nil {
+		return libvirtError("error adding the SCSI controller", err)
+	}
+	log.Printf("added virtio-scsi controller %d to the domain %s", index, dom.Name)
+	return nil
+}
+
+// FindDomainBySource returns the running domain and its device the image is attached to,
+// or empty strings when the image is not attached anywhere
+func (k *Kvm) FindDomainBySource(sourceFile string) (string, string, error) {
+	return k.findDomainBySource(sourceFile, libvirt.ConnectListDomainsActive)
+}
+
+// FindDefinedDomainBySource returns the domain and its device the image is attached to, the domain
+// may be shut off as well. Empty strings are returned when the image is not attached anywhere.
+func (k *Kvm) FindDefinedDomainBySource(sourceFile string) (string, string, error) {
+	return k.findDomainBySource(sourceFile, libvirt.ConnectListDomainsActive|libvirt.ConnectListDomainsInactive)
+}
+
+func (k *Kvm) findDomainBySource(sourceFile string, flags libvirt.ConnectListAllDomainsFlags) (string, string, error) {
+	domains, _, err := k.l.ConnectListAllDomains(1, flags)
+	if err != nil {
+		return "", "", libvirtError("error listing the domains", err)
+	}
+
+	for _, domain := range domains {
+		dom, err := k.getDomain(domain.Name)
+		if err != nil {
+			return "", "", err
+		}
+		for _, disk := range dom.Devices.Disks {
+			if disk.sourcePath() == sourceFile {
+				return domain.Name, disk.Target.Dev, nil
+			}
+		}
 	}
 
-	nextDevice := k.getNextAvailableDevice(dom)
-	return nextDevice, nil
+	return "", "", nil
 }
 
-func (k *Kvm) prepareNewDiskXML(filepath string, targetDevice string) ([]byte, error) {
+// GetAttachedImages maps the images attached to the domains to the names of those domains,
+// the disks of shut off domains are attached again once they start
+func (k *Kvm) GetAttachedImages() (map[string][]string, error) {
+	domains, _, err := k.l.ConnectListAllDomains(1, libvirt.ConnectListDomainsActive|libvirt.ConnectListDomainsInactive)
+	if err != nil {
+		return nil, libvirtError("error listing the domains", err)
+	}
+
+	attachedImages := make(map[string][]string)
+	for _, domain := range domains {
+		dom, err := k.getDomain(domain.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, disk := range dom.Devices.Disks {
+			if source := disk.sourcePath(); source != "" {
+				attachedImages[source] = append(attachedImages[source], domain.Name)
+			}
+		}
+	}
+
+	return attachedImages, nil
+}
+
+func (k *Kvm) prepareNewDiskXML(volume Volume, targetDevice string, serial string, address *DriveAddress, settings DiskSettings, readonly bool) ([]byte, error) {
 	// Create the new disk element
 	newDisk := Disk{
 		Type:   "file",
 		Device: "disk",
 	}
 	newDisk.Driver.Name = "qemu"
-	newDisk.Driver.Type = "qcow2"
-	newDisk.Source.File = filepath    // path to your QCOW2 file
+	newDisk.Driver.Type = settings.Format
+	if newDisk.Driver.Type == "" {
+		// raw disks are never probed for a header the guest could have written
+		newDisk.Driver.Type = RawFormat
+	}
+	newDisk.Driver.Cache = settings.Cache
+	if volume.Block {
+		// volumes of LVM and disk pools are block devices
+		newDisk.Type = "block"
+		newDisk.Source.Dev = volume.Path
+	} else {
+		newDisk.Source.File = volume.Path // path to your QCOW2 file
+	}
 	newDisk.Target.Dev = targetDevice // The device name to be used (sdX for SCSI devices)
 	newDisk.Target.Bus = "scsi"       // Use the 'scsi' bus as 'virtio' does not fully support hotplug
+	newDisk.Serial = serial           // lets the guest find the disk whatever name its kernel gives it
+	newDisk.Address = address
+	if readonly {
+		newDisk.ReadOnly = &struct{}{}
+	}
+	if settings.IoTune != (IoTune{}) {
+		newDisk.IoTune = &settings.IoTune
+	}
 
 	// Convert the new disk back to XML
 	newDiskXML, err := xml.Marshal(newDisk)
@@ -162,61 +441,185 @@ func (k *Kvm) prepareNewDiskXML(filepath string, targetDevice string) ([]byte, e
 	return newDiskXML, nil
 }
 
-func (k *Kvm) CreateVolume(filepath string, size int64) error {
-	// return qcow2.Create(filepath, size)
-	cmd := exec.Command("qemu-img", "create", "-f", "qcow2", filepath, fmt.Sprintf("%d", size))
+// getDiskXML returns the disk as defined in the domain, including its driver settings and I/O limits
+func (k *Kvm) getDiskXML(domainName string, filepath string, targetDevice string) ([]byte, error) {
+	dom, err := k.getDomain(domainName)
+	if err != nil {
+		return nil, err
+	}
+	for _, disk := range dom.Devices.Disks {
+		if disk.sourcePath() == filepath && disk.Target.Dev == targetDevice {
+			return xml.Marshal(disk)
+		}
+	}
+	return k.prepareNewDiskXML(Volume{Path: filepath}, targetDevice, "", nil, DiskSettings{}, false)
+}
+
+// CheckImage checks the metadata of the image, which is read in the given format rather than in the
+// one probed from its contents
+func (k *Kvm) CheckImage(filepath string, format string) (ImageCheck, error) {
+	if err := checkImageExists(filepath); err != nil {
+		return ImageCheck{}, err
+	}
+	cmd := exec.Command("qemu-img", "check", "-U", "-f", format, "--output=json", filepath)
+	// qemu-img check exits non-zero when it finds any problem, the JSON report is printed anyway
 	stdout, err := cmd.Output()
-	log.Printf("image creation output: %s", stdout)
+	if len(stdout) == 0 && err != nil {
+		return ImageCheck{}, fmt.Errorf("error checking the image: %w", err)
+	}
+	var check ImageCheck
+	err = json.Unmarshal(stdout, &check)
 	if err != nil {
-		return err
+		return ImageCheck{}, fmt.Errorf("error unmarshalling the image check: %w", err)
 	}
-	err = os.Chown(filepath, 107, 107)
+	return check, nil
+}
+
+func (k *Kvm) ResizeAttachedVolume(domainName string, targetDevice string, size int64) error {
+	dom, err := k.getDomainByName(domainName)
 	if err != nil {
 		return err
 	}
-
+	err = k.l.DomainBlockResize(dom, targetDevice, uint64(size), libvirt.DomainBlockResizeBytes)
+	if err != nil {
+		return libvirtError("error resizing the device", err)
+	}
 	return nil
 }
 
-func (k *Kvm) AttachVolumeToDomain(domainName string, filepath string, targetDevice string) error {
-	rPool, err := k.l.StoragePoolLookupByName("default") //TODO - default must come from a parameter
+// AttachVolumeToDomain attaches the image to the next free device of the domain and returns the device name.
+// The disk gets the format of the settings rather than the one detected from the image. The caller has
+// to make sure no other disk gets attached to the domain meanwhile.
+func (k *Kvm) AttachVolumeToDomain(domainName string, volume Volume, serial string, settings DiskSettings, readonly bool) (string, error) {
+	dom, err := k.getDomainByName(domainName)
 	if err != nil {
-		log.Fatal(err)
+		return "", err
+	}
+	domain, err := k.getDomain(domainName)
+	if err != nil {
+		return "", err
 	}
+	flags, err := k.deviceModifyFlags(dom)
+	if err != nil {
+		return "", err
+	}
+	if flags == libvirt.DomainDeviceModifyLive|libvirt.DomainDeviceModifyConfig {
+		// the device slot has to be free in the persistent definition too
+		config, err := k.getDomainConfig(dom)
+		if err != nil {
+			return "", err
+		}
+		domain.Devices.Disks = append(domain.Devices.Disks, config.Devices.Disks...)
+	}
+	targetDevice, address, err := k.getNextAvailableDevice(domain)
+	if err != nil {
+		return "", fmt.Errorf("%w: domain %s", err, domainName)
+	}
+	if !slices.ContainsFunc(domain.Devices.Controllers, func(controller Controller) bool {
+		return controller.Type == "scsi" && controller.Index == address.Controller
+	}) {
+		// all the virtio-scsi controllers are full
+		err = k.addController(dom, address.Controller, flags)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	newDiskXML, err := k.prepareNewDiskXML(volume, targetDevice, serial, &address, settings, readonly)
+	if err != nil {
+		return "", fmt.Errorf("error preparing the new disk XML: %w", err)
+	}
+	err = k.l.DomainAttachDeviceFlags(dom, string(newDiskXML), uint32(flags))
+	if err != nil {
+		return "", libvirtError("error attaching the device", err)
+	}
+
+	return targetDevice, nil
+}
+
+// IsDiskReadOnly reports whether the disk of the domain is attached read-only
+func (k *Kvm) IsDiskReadOnly(domainName string, targetDevice string) (bool, error) {
+	dom, err := k.getDomain(domainName)
+	if err != nil {
+		return false, err
+	}
+	for _, disk := range dom.Devices.Disks {
+		if disk.Target.Dev == targetDevice {
+			return disk.ReadOnly != nil, nil
+		}
+	}
+	return false, fmt.Errorf("%w: no disk %s in the domain %s", ErrImageNotFound, targetDevice, domainName)
+}
 
-	err = k.l.StoragePoolRefresh(rPool, 0)
+// GetDiskSettings returns the I/O limits and the cache mode of the disk of the domain
+func (k *Kvm) GetDiskSettings(domainName string, targetDevice string) (DiskSettings, error) {
+	dom, err := k.getDomain(domainName)
 	if err != nil {
-		log.Fatal(err)
+		return DiskSettings{}, err
 	}
+	for _, disk := range dom.Devices.Disks {
+		if disk.Target.Dev != targetDevice {
+			continue
+		}
+		settings := DiskSettings{Cache: disk.Driver.Cache}
+		if disk.IoTune != nil {
+			settings.IoTune = *disk.IoTune
+		}
+		return settings, nil
+	}
+	return DiskSettings{}, fmt.Errorf("%w: no disk %s in the domain %s", ErrImageNotFound, targetDevice, domainName)
+}
 
+func (k *Kvm) DetachVolumeFromDomain(domainName string, filepath string, targetDevice string) error {
 	dom, err := k.getDomainByName(domainName)
 	if err != nil {
 		return err
 	}
-	newDiskXML, err := k.prepareNewDiskXML(filepath, targetDevice)
+	diskXML, err := k.getDiskXML(domainName, filepath, targetDevice)
 	if err != nil {
-		return fmt.Errorf("error preparing the new disk XML: %w", err)
+		return err
 	}
-	err = k.l.DomainAttachDevice(dom, string(newDiskXML))
+	flags, err := k.diskModifyFlags(dom, targetDevice)
 	if err != nil {
-		return fmt.Errorf("error attaching the device: %w", err)
+		return err
+	}
+	err = k.l.DomainDetachDeviceFlags(dom, string(diskXML), uint32(flags))
+	if err != nil {
+		return libvirtError("error detaching the device", err)
 	}
-
 	return nil
 }
 
-func (k *Kvm) DetachVolumeFromDomain(domainName string, filepath string, targetDevice string) error {
+func (k *Kvm) SetIoTune(domainName string, targetDevice string, ioTune IoTune) error {
 	dom, err := k.getDomainByName(domainName)
 	if err != nil {
 		return err
 	}
-	newDiskXML, err := k.prepareNewDiskXML(filepath, targetDevice)
+	// all the limits are passed, so the ones not set anymore get reset to unlimited
+	limits := map[string]uint64{
+		"total_bytes_sec": ioTune.TotalBytesSec,
+		"read_bytes_sec":  ioTune.ReadBytesSec,
+		"write_bytes_sec": ioTune.WriteBytesSec,
+		"total_iops_sec":  ioTune.TotalIopsSec,
+		"read_iops_sec":   ioTune.ReadIopsSec,
+		"write_iops_sec":  ioTune.WriteIopsSec,
+	}
+	var params []libvirt.TypedParam
+	for name, value := range limits {
+		params = append(params, libvirt.TypedParam{
+			Field: name,
+			Value: *libvirt.NewTypedParamValueUllong(value),
+		})
+	}
+	// the limits are kept in the persistent definition too, libvirt.DomainAffectLive and
+	// libvirt.DomainAffectConfig have the values of the device modify flags
+	flags, err := k.diskModifyFlags(dom, targetDevice)
 	if err != nil {
 		return err
 	}
-	err = k.l.DomainDetachDevice(dom, string(newDiskXML))
+	err = k.l.DomainSetBlockIOTune(dom, targetDevice, params, uint32(flags))
 	if err != nil {
-		return err
+		return libvirtError("error setting the I/O limits of the device", err)
 	}
 	return nil
 }
@@ -0,0 +1,151 @@
+package kvm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDeviceName(t *testing.T) {
+	tests := []struct {
+		index int
+		name  string
+	}{
+		{index: 0, name: "sda"},
+		{index: 1, name: "sdb"},
+		{index: 25, name: "sdz"},
+		{index: 26, name: "sdaa"},
+		{index: 27, name: "sdab"},
+		{index: 51, name: "sdaz"},
+		{index: 52, name: "sdba"},
+		{index: maxDevices - 1, name: "sdzz"},
+	}
+	for _, tt := range tests {
+		if name := deviceName(tt.index); name != tt.name {
+			t.Errorf("deviceName(%d) = %s, expected %s", tt.index, name, tt.name)
+		}
+	}
+}
+
+// scsiDisk returns a disk attached to the given target of a SCSI controller
+func scsiDisk(dev string, controller int, target int) Disk {
+	disk := Disk{Address: &DriveAddress{Type: "drive", Controller: controller, Target: target}}
+	disk.Target.Dev = dev
+	disk.Target.Bus = "scsi"
+	return disk
+}
+
+// sataDisk returns a disk which is not attached to a SCSI controller
+func sataDisk(dev string) Disk {
+	disk := Disk{}
+	disk.Target.Dev = dev
+	disk.Target.Bus = "sata"
+	return disk
+}
+
+// fullController returns the disks taking all the targets of a controller, named from the given index on
+func fullController(controller int, firstIndex int) []Disk {
+	var disks []Disk
+	for target := 0; target < virtioSCSITargets; target++ {
+		disks = append(disks, scsiDisk(deviceName(firstIndex+target), controller, target))
+	}
+	return disks
+}
+
+func TestGetNextAvailableDevice(t *testing.T) {
+	virtioSCSI := func(index int) Controller {
+		return Controller{Type: "scsi", Index: index, Model: "virtio-scsi"}
+	}
+	allNames := func() []Disk {
+		var disks []Disk
+		for index := 0; index < maxDevices; index++ {
+			disk := Disk{}
+			disk.Target.Dev = deviceName(index)
+			disks = append(disks, disk)
+		}
+		return disks
+	}
+
+	tests := []struct {
+		name    string
+		domain  Domain
+		device  string
+		address DriveAddress
+		err     error
+	}{
+		{
+			name:    "no disks",
+			domain:  Domain{Devices: Devices{Controllers: []Controller{virtioSCSI(0)}}},
+			device:  "sda",
+			address: DriveAddress{Type: "drive", Controller: 0, Target: 0},
+		},
+		{
+			name: "used targets skipped",
+			domain: Domain{Devices: Devices{
+				Disks:       []Disk{scsiDisk("sda", 0, 0), scsiDisk("sdb", 0, 1), scsiDisk("sdd", 0, 3)},
+				Controllers: []Controller{virtioSCSI(0)},
+			}},
+			device:  "sdc",
+			address: DriveAddress{Type: "drive", Controller: 0, Target: 2},
+		},
+		{
+			name: "disks of other buses only take their names",
+			domain: Domain{Devices: Devices{
+				Disks:       []Disk{sataDisk("sda")},
+				Controllers: []Controller{virtioSCSI(0)},
+			}},
+			device:  "sdb",
+			address: DriveAddress{Type: "drive", Controller: 0, Target: 0},
+		},
+		{
+			name: "next controller of a full one",
+			domain: Domain{Devices: Devices{
+				Disks:       fullController(0, 0),
+				Controllers: []Controller{virtioSCSI(0)},
+			}},
+			device:  deviceName(virtioSCSITargets),
+			address: DriveAddress{Type: "drive", Controller: 1},
+		},
+		{
+			name: "free target of the second controller",
+			domain: Domain{Devices: Devices{
+				Disks:       append(fullController(0, 0), scsiDisk(deviceName(virtioSCSITargets), 1, 0)),
+				Controllers: []Controller{virtioSCSI(0), virtioSCSI(1)},
+			}},
+			device:  deviceName(virtioSCSITargets + 1),
+			address: DriveAddress{Type: "drive", Controller: 1, Target: 1},
+		},
+		{
+			name: "controllers of other models skipped",
+			domain: Domain{Devices: Devices{
+				Controllers: []Controller{{Type: "scsi", Index: 0, Model: "lsilogic"}, {Type: "usb", Index: 3}},
+			}},
+			device:  "sda",
+			address: DriveAddress{Type: "drive", Controller: 1},
+		},
+		{
+			name: "all names used",
+			domain: Domain{Devices: Devices{
+				Disks:       allNames(),
+				Controllers: []Controller{virtioSCSI(0)},
+			}},
+			err: ErrNoFreeDevice,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device, address, err := (&Kvm{}).getNextAvailableDevice(tt.domain)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected the error %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if device != tt.device || address != tt.address {
+				t.Errorf("got (%s, %+v), expected (%s, %+v)", device, address, tt.device, tt.address)
+			}
+		})
+	}
+}
@@ -0,0 +1,448 @@
+package kvm
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/digitalocean/go-libvirt"
+	"log"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// DefaultPool is the storage pool used when no other one is configured
+const DefaultPool = "default"
+
+// Volume describes a volume of the storage pool
+type Volume struct {
+	Name        string
+	Path        string
+	Format      string
+	Capacity    int64
+	Allocation  int64
+	Block       bool // the volume is a block device rather than a file
+	BackingPath string
+}
+
+// DetectedFormat returns the format libvirt detected from the contents of the volume, the volumes of
+// LVM and disk pools have none reported as they hold raw data. The contents of a raw image are written
+// by the guest, so the detected format is only to be trusted right after the volume was created.
+func (v Volume) DetectedFormat() string {
+	if v.Format == "" {
+		return RawFormat
+	}
+	return v.Format
+}
+
+// volumeXML is the part of the libvirt storage volume XML used by the driver
+type volumeXML struct {
+	XMLName    xml.Name    `xml:"volume"`
+	Name       string      `xml:"name"`
+	Capacity   sizeXML     `xml:"capacity"`
+	Allocation *sizeXML    `xml:"allocation,omitempty"`
+	Target     targetXML   `xml:"target"`
+	Backing    *backingXML `xml:"backingStore,omitempty"`
+}
+
+type backingXML struct {
+	Path   string    `xml:"path"`
+	Format formatXML `xml:"format"`
+}
+
+type targetXML struct {
+	Path        string       `xml:"path,omitempty"`
+	Format      formatXML    `xml:"format"`
+	ClusterSize *sizeXML     `xml:"clusterSize,omitempty"`
+	Compat      string       `xml:"compat,omitempty"`
+	Features    *featuresXML `xml:"features,omitempty"`
+}
+
+type featuresXML struct {
+	LazyRefcounts *struct{} `xml:"lazy_refcounts"`
+}
+
+type formatXML struct {
+	Type string `xml:"type,attr"`
+}
+
+type sizeXML struct {
+	Unit  string `xml:"unit,attr,omitempty"`
+	Value int64  `xml:",chardata"`
+}
+
+// blockCopyTargetXML is the volume a disk of a domain gets copied into
+type blockCopyTargetXML struct {
+	XMLName xml.Name `xml:"disk"`
+	Type    string   `xml:"type,attr"`
+	Driver  struct {
+		Type string `xml:"type,attr"`
+	} `xml:"driver"`
+	Source struct {
+		File string `xml:"file,attr,omitempty"`
+		Dev  string `xml:"dev,attr,omitempty"`
+	} `xml:"source"`
+}
+
+// blockJobPollInterval is the time between the checks of the progress of a block job
+const blockJobPollInterval = time.Second
+
+// clusterSizePattern matches the cluster sizes accepted by qemu-img, e.g. 65536, 64k or 2M
+var clusterSizePattern = regexp.MustCompile(`^([0-9]+)([kKM]?)$`)
+
+// parseClusterSize converts the cluster size to bytes
+func parseClusterSize(clusterSize string) (int64, error) {
+	match := clusterSizePattern.FindStringSubmatch(clusterSize)
+	if match == nil {
+		return 0, fmt.Errorf("invalid cluster size %s", clusterSize)
+	}
+	size, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cluster size %s: %w", clusterSize, err)
+	}
+	switch match[2] {
+	case "k", "K":
+		size <<= 10
+	case "M":
+		size <<= 20
+	}
+	return size, nil
+}
+
+// volumeError wraps the error of a libvirt storage call, a missing volume becomes ErrImageNotFound
+func volumeError(message string, name string, err error) error {
+	var libvirtErr libvirt.Error
+	if errors.As(err, &libvirtErr) && libvirtErr.Code == uint32(libvirt.ErrNoStorageVol) {
+		return fmt.Errorf("%s %s: %w: %w", message, name, ErrImageNotFound, err)
+	}
+	return libvirtError(fmt.Sprintf("%s %s", message, name), err)
+}
+
+func (k *Kvm) getPool() (libvirt.StoragePool, error) {
+	pool, err := k.l.StoragePoolLookupByName(k.poolName())
+	if err != nil {
+		return libvirt.StoragePool{}, libvirtError(fmt.Sprintf("error looking up the storage pool %s", k.poolName()), err)
+	}
+	return pool, nil
+}
+
+func (k *Kvm) poolName() string {
+	if k.Pool == "" {
+		return DefaultPool
+	}
+	return k.Pool
+}
+
+// RefreshPool makes libvirt scan the pool again, so that it learns about the files changed outside of it
+func (k *Kvm) RefreshPool() error {
+	pool, err := k.getPool()
+	if err != nil {
+		return err
+	}
+	err = k.l.StoragePoolRefresh(pool, 0)
+	if err != nil {
+		return libvirtError(fmt.Sprintf("error refreshing the storage pool %s", pool.Name), err)
+	}
+	return nil
+}
+
+// GetPoolCapacity returns the total and the available bytes of the pool
+func (k *Kvm) GetPoolCapacity() (int64, int64, error) {
+	pool, err := k.getPool()
+	if err != nil {
+		return 0, 0, err
+	}
+	_, capacity, _, available, err := k.l.StoragePoolGetInfo(pool)
+	if err != nil {
+		return 0, 0, libvirtError(fmt.Sprintf("error getting the capacity of the storage pool %s", pool.Name), err)
+	}
+	return int64(capacity), int64(available), nil
+}
+
+// ListVolumes returns the names of all the volumes of the pool
+func (k *Kvm) ListVolumes() ([]string, error) {
+	pool, err := k.getPool()
+	if err != nil {
+		return nil, err
+	}
+	vols, _, err := k.l.StoragePoolListAllVolumes(pool, 1, 0)
+	if err != nil {
+		return nil, libvirtError(fmt.Sprintf("error listing the volumes of the storage pool %s", pool.Name), err)
+	}
+	names := make([]string, 0, len(vols))
+	for _, vol := range vols {
+		names = append(names, vol.Name)
+	}
+	return names, nil
+}
+
+// LookupVolume returns the volume of the pool, failing with ErrImageNotFound when it does not exist
+func (k *Kvm) LookupVolume(name string) (Volume, error) {
+	pool, err := k.getPool()
+	if err != nil {
+		return Volume{}, err
+	}
+	vol, err := k.l.StorageVolLookupByName(pool, name)
+	if err != nil {
+		return Volume{}, volumeError("error looking up the volume", name, err)
+	}
+	return k.describeVolume(vol)
+}
+
+func (k *Kvm) describeVolume(vol libvirt.StorageVol) (Volume, error) {
+	volType, capacity, allocation, err := k.l.StorageVolGetInfo(vol)
+	if err != nil {
+		return Volume{}, volumeError("error getting the info of the volume", vol.Name, err)
+	}
+	desc, err := k.l.StorageVolGetXMLDesc(vol, 0)
+	if err != nil {
+		return Volume{}, volumeError("error getting the XML of the volume", vol.Name, err)
+	}
+	var volXML volumeXML
+	err = xml.Unmarshal([]byte(desc), &volXML)
+	if err != nil {
+		return Volume{}, fmt.Errorf("error unmarshalling the XML of the volume %s: %w", vol.Name, err)
+	}
+
+	volume := Volume{
+		Name:       vol.Name,
+		Path:       volXML.Target.Path,
+		Format:     volXML.Target.Format.Type,
+		Capacity:   int64(capacity),
+		Allocation: int64(allocation),
+		Block:      volType == int8(libvirt.StorageVolBlock),
+	}
+	if volXML.Backing != nil {
+		volume.BackingPath = volXML.Backing.Path
+	}
+	return volume, nil
+}
+
+// newVolumeXML returns the definition of a new volume and the flags creating it. The metadata
+// preallocation is done by qemu-img called by libvirt, falloc and full preallocation both allocate
+// the whole capacity of the volume.
+func newVolumeXML(name string, size int64, options ImageOptions) (volumeXML, libvirt.StorageVolCreateFlags, error) {
+	volXML := volumeXML{
+		Name:       name,
+		Capacity:   sizeXML{Unit: "bytes", Value: size},
+		Allocation: &sizeXML{Unit: "bytes", Value: 0},
+		Target:     targetXML{Format: formatXML{Type: options.format()}},
+	}
+	var flags libvirt.StorageVolCreateFlags
+	// libvirt allocates the whole capacity when the allocation is left out
+	switch options.Preallocation {
+	case "metadata":
+		flags |= libvirt.StorageVolCreatePreallocMetadata
+	case "falloc", "full":
+		volXML.Allocation = &sizeXML{Unit: "bytes", Value: size}
+		if options.format() == DefaultImageFormat {
+			// libvirt preallocates the qcow2 images only when the metadata are preallocated too
+			flags |= libvirt.StorageVolCreatePreallocMetadata
+		}
+	}
+	if options.ClusterSize != "" {
+		clusterSize, err := parseClusterSize(options.ClusterSize)
+		if err != nil {
+			return volumeXML{}, 0, err
+		}
+		volXML.Target.ClusterSize = &sizeXML{Unit: "B", Value: clusterSize}
+	}
+	if options.LazyRefcounts {
+		volXML.Target.Compat = "1.1"
+		volXML.Target.Features = &featuresXML{LazyRefcounts: &struct{}{}}
+	}
+	return volXML, flags, nil
+}
+
+// CreateVolume creates an empty volume in the pool
+func (k *Kvm) CreateVolume(name string, size int64, options ImageOptions) (Volume, error) {
+	volXML, flags, err := newVolumeXML(name, size, options)
+	if err != nil {
+		return Volume{}, err
+	}
+	return k.createVolume(volXML, nil, flags)
+}
+
+// CreateLinkedVolume creates a thin qcow2 overlay on top of the backing volume
+func (k *Kvm) CreateLinkedVolume(backing Volume, name string, size int64) (Volume, error) {
+	volXML := volumeXML{
+		Name:       name,
+		Capacity:   sizeXML{Unit: "bytes", Value: size},
+		Allocation: &sizeXML{Unit: "bytes", Value: 0},
+		Target:     targetXML{Format: formatXML{Type: DefaultImageFormat}},
+		Backing:    &backingXML{Path: backing.Path, Format: formatXML{Type: backing.Format}},
+	}
+	return k.createVolume(volXML, nil, 0)
+}
+
+// createVolume creates the volume, filled with the data of the source volume unless it is nil
+func (k *Kvm) createVolume(volXML volumeXML, source *libvirt.StorageVol, flags libvirt.StorageVolCreateFlags) (Volume, error) {
+	pool, err := k.getPool()
+	if err != nil {
+		return Volume{}, err
+	}
+	desc, err := xml.Marshal(volXML)
+	if err != nil {
+		return Volume{}, fmt.Errorf("error marshalling the XML of the volume %s: %w", volXML.Name, err)
+	}
+	var vol libvirt.StorageVol
+	if source == nil {
+		vol, err = k.l.StorageVolCreateXML(pool, string(desc), flags)
+	} else {
+		vol, err = k.l.StorageVolCreateXMLFrom(pool, string(desc), *source, flags)
+	}
+	if err != nil {
+		return Volume{}, libvirtError(fmt.Sprintf("error creating the volume %s", volXML.Name), err)
+	}
+	return k.describeVolume(vol)
+}
+
+// CopyVolume creates a volume of the given size holding the data of the source volume. libvirt copies
+// the data, it fails to read an image locked by a running domain.
+func (k *Kvm) CopyVolume(source Volume, name string, size int64, options ImageOptions) (Volume, error) {
+	pool, err := k.getPool()
+	if err != nil {
+		return Volume{}, err
+	}
+	sourceVol, err := k.l.StorageVolLookupByName(pool, source.Name)
+	if err != nil {
+		return Volume{}, volumeError("error looking up the volume", source.Name, err)
+	}
+	volXML, flags, err := newVolumeXML(name, source.Capacity, options)
+	if err != nil {
+		return Volume{}, err
+	}
+	volume, err := k.createVolume(volXML, &sourceVol, flags)
+	if err != nil {
+		return Volume{}, err
+	}
+	return k.growCopy(volume, size, options)
+}
+
+// growCopy resizes the copy requested bigger than its source, the copy is deleted when it can't be resized
+func (k *Kvm) growCopy(volume Volume, size int64, options ImageOptions) (Volume, error) {
+	if size <= volume.Capacity {
+		return volume, nil
+	}
+	var resizeFlags libvirt.StorageVolResizeFlags
+	if options.Preallocation == "falloc" || options.Preallocation == "full" {
+		resizeFlags |= libvirt.StorageVolResizeAllocate
+	}
+	err := k.resizeVolume(volume.Name, size, resizeFlags)
+	if err != nil {
+		if deleteErr := k.DeleteVolume(volume.Name); deleteErr != nil {
+			log.Printf("error while deleting the incomplete volume %s: %v", volume.Name, deleteErr)
+		}
+		return Volume{}, err
+	}
+	// libvirt may round the capacity up
+	return k.LookupVolume(volume.Name)
+}
+
+// CopyAttachedVolume creates a volume of the given size holding the data of the source volume, which is
+// the disk of the running domain. QEMU mirrors the disk into the volume while the guest keeps writing to it,
+// so the volume holds the data of the disk as of the moment the mirror is stopped, the same as the disk
+// after a crash of the guest.
+func (k *Kvm) CopyAttachedVolume(domainName string, targetDevice string, source Volume, name string, size int64, options ImageOptions) (Volume, error) {
+	dom, err := k.getDomainByName(domainName)
+	if err != nil {
+		return Volume{}, err
+	}
+	// QEMU mirrors the disk into a volume of the same size only
+	volume, err := k.CreateVolume(name, source.Capacity, options)
+	if err != nil {
+		return Volume{}, err
+	}
+	err = k.mirrorDisk(dom, targetDevice, volume)
+	if err != nil {
+		if deleteErr := k.DeleteVolume(name); deleteErr != nil {
+			log.Printf("error while deleting the incomplete volume %s: %v", name, deleteErr)
+		}
+		return Volume{}, err
+	}
+	return k.growCopy(volume, size, options)
+}
+
+// mirrorDisk copies the disk along with its backing files into the volume, the mirror is stopped
+// as soon as the volume caught up with the disk
+func (k *Kvm) mirrorDisk(dom libvirt.Domain, targetDevice string, volume Volume) error {
+	target := blockCopyTargetXML{Type: "file"}
+	target.Driver.Type = volume.DetectedFormat()
+	if volume.Block {
+		target.Type = "block"
+		target.Source.Dev = volume.Path
+	} else {
+		target.Source.File = volume.Path
+	}
+	targetXML, err := xml.Marshal(target)
+	if err != nil {
+		return fmt.Errorf("error marshalling the XML of the volume %s: %w", volume.Name, err)
+	}
+
+	// the volume created by the pool is reused, so that it keeps the ownership and the labels set by libvirt,
+	// the job is transient as libvirt refuses copying the disks of persistent domains otherwise
+	flags := libvirt.DomainBlockCopyReuseExt | libvirt.DomainBlockCopyTransientJob
+	err = k.l.DomainBlockCopy(dom, targetDevice, string(targetXML), nil, flags)
+	if err != nil {
+		return libvirtError("error starting the copy of the device", err)
+	}
+	for {
+		found, _, _, cur, end, err := k.l.DomainGetBlockJobInfo(dom, targetDevice, 0)
+		if err != nil {
+			_ = k.l.DomainBlockJobAbort(dom, targetDevice, 0)
+			return libvirtError("error getting the progress of the copy of the device", err)
+		}
+		if found == 0 {
+			return fmt.Errorf("copy of the device %s to the volume %s stopped before it was complete", targetDevice, volume.Name)
+		}
+		if end > 0 && cur == end {
+			break
+		}
+		time.Sleep(blockJobPollInterval)
+	}
+
+	// aborting the job rather than pivoting to the volume keeps the domain on its disk
+	err = k.l.DomainBlockJobAbort(dom, targetDevice, 0)
+	if err != nil {
+		return libvirtError("error stopping the copy of the device", err)
+	}
+	return nil
+}
+
+// ResizeVolume grows the volume, which must not be used by a running domain
+func (k *Kvm) ResizeVolume(name string, size int64) error {
+	return k.resizeVolume(name, size, 0)
+}
+
+func (k *Kvm) resizeVolume(name string, size int64, flags libvirt.StorageVolResizeFlags) error {
+	pool, err := k.getPool()
+	if err != nil {
+		return err
+	}
+	vol, err := k.l.StorageVolLookupByName(pool, name)
+	if err != nil {
+		return volumeError("error looking up the volume", name, err)
+	}
+	err = k.l.StorageVolResize(vol, uint64(size), flags)
+	if err != nil {
+		return libvirtError(fmt.Sprintf("error resizing the volume %s", name), err)
+	}
+	return nil
+}
+
+// DeleteVolume deletes the volume from the pool, failing with ErrImageNotFound when it does not exist
+func (k *Kvm) DeleteVolume(name string) error {
+	pool, err := k.getPool()
+	if err != nil {
+		return err
+	}
+	vol, err := k.l.StorageVolLookupByName(pool, name)
+	if err != nil {
+		return volumeError("error looking up the volume", name, err)
+	}
+	err = k.l.StorageVolDelete(vol, libvirt.StorageVolDeleteNormal)
+	if err != nil {
+		return volumeError("error deleting the volume", name, err)
+	}
+	return nil
+}
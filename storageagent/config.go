@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/digitalocean/go-libvirt"
+	"github.com/onlineque/kvmCsiDriver/pkg/kvm"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// DefaultConfigPath is the config file read when no other one is given
+const DefaultConfigPath = "/etc/storageagent/config.yaml"
+
+// Config holds the settings of the storage agent. The directory of the images and their ownership
+// come from the definition of the libvirt storage pool.
+type Config struct {
+	// Listen is the address the gRPC server listens on
+	Listen string `yaml:"listen"`
+	// LibvirtURI is the libvirt connection URI
+	LibvirtURI string `yaml:"libvirtURI"`
+	// Pool is the libvirt storage pool keeping the images
+	Pool string `yaml:"pool"`
+	// SettingsDir keeps the settings of the volumes, which have to survive detaching them
+	SettingsDir string `yaml:"settingsDir"`
+	// MaxLibvirtCalls limits the requests using the libvirt connection at once
+	MaxLibvirtCalls int `yaml:"maxLibvirtCalls"`
+}
+
+// defaultConfig returns the settings used for everything the config file and the flags leave out
+func defaultConfig() Config {
+	return Config{
+		Listen:          ":7003",
+		LibvirtURI:      string(libvirt.QEMUSystem),
+		Pool:            kvm.DefaultPool,
+		SettingsDir:     "/var/lib/storageagent",
+		MaxLibvirtCalls: 8,
+	}
+}
+
+// loadConfig reads the config file over the defaults. The default config file may be missing.
+func loadConfig(path string, required bool) (Config, error) {
+	config := defaultConfig()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && !required {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("error reading the config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	// a mistyped setting would be ignored silently otherwise
+	decoder.KnownFields(true)
+	err = decoder.Decode(&config)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return config, fmt.Errorf("error parsing the config file %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// validate checks the settings before the agent starts using them
+func (c Config) validate() error {
+	_, port, err := net.SplitHostPort(c.Listen)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %q: %w", c.Listen, err)
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("invalid port of the listen address %q", c.Listen)
+	}
+	uri, err := url.Parse(c.LibvirtURI)
+	if err != nil || uri.Scheme == "" {
+		return fmt.Errorf("invalid libvirt URI %q", c.LibvirtURI)
+	}
+	if c.Pool == "" {
+		return errors.New("the storage pool must be set")
+	}
+	if !filepath.IsAbs(c.SettingsDir) {
+		return fmt.Errorf("the settings directory %q must be an absolute path", c.SettingsDir)
+	}
+	if c.MaxLibvirtCalls < 1 {
+		return fmt.Errorf("the maximum of libvirt calls must be positive, not %d", c.MaxLibvirtCalls)
+	}
+	return nil
+}
+
+// String renders the settings as a config file
+func (c Config) String() string {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/onlineque/kvmCsiDriver/pkg/kvm"
+	sa "github.com/onlineque/kvmCsiDriver/storageagent_proto"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// newRootCmd returns the storageagent command, the flags take precedence over the config file
+func newRootCmd() *cobra.Command {
+	var configPath string
+	var printConfig bool
+	flagConfig := defaultConfig()
+
+	rootCmd := &cobra.Command{
+		Use:   "storageagent",
+		Short: "Starts the StorageAgent component of KVM CSI Driver",
+		Long: `KVM CSI Driver StorageAgent component
+
+StorageAgent runs on the KVM host, it creates the volumes in a libvirt storage pool
+and attaches them to the domains running the Kubernetes nodes`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := loadConfig(configPath, cmd.Flags().Changed("config"))
+			if err != nil {
+				return err
+			}
+			flags := cmd.Flags()
+			if flags.Changed("listen") {
+				config.Listen = flagConfig.Listen
+			}
+			if flags.Changed("libvirt-uri") {
+				config.LibvirtURI = flagConfig.LibvirtURI
+			}
+			if flags.Changed("pool") {
+				config.Pool = flagConfig.Pool
+			}
+			if flags.Changed("settings-dir") {
+				config.SettingsDir = flagConfig.SettingsDir
+			}
+			if flags.Changed("max-libvirt-calls") {
+				config.MaxLibvirtCalls = flagConfig.MaxLibvirtCalls
+			}
+
+			err = config.validate()
+			if err != nil {
+				return fmt.Errorf("invalid configuration: %w", err)
+			}
+			if printConfig {
+				fmt.Print(config)
+				return nil
+			}
+			return run(config)
+		},
+	}
+
+	flags := rootCmd.Flags()
+	flags.StringVar(&configPath, "config", DefaultConfigPath, "config file")
+	flags.BoolVar(&printConfig, "print-config", false, "print the effective configuration and exit")
+	flags.StringVar(&flagConfig.Listen, "listen", flagConfig.Listen, "address the gRPC server listens on")
+	flags.StringVar(&flagConfig.LibvirtURI, "libvirt-uri", flagConfig.LibvirtURI, "libvirt connection URI")
+	flags.StringVar(&flagConfig.Pool, "pool", flagConfig.Pool, "libvirt storage pool keeping the images")
+	flags.StringVar(&flagConfig.SettingsDir, "settings-dir", flagConfig.SettingsDir, "directory keeping the settings of the volumes")
+	flags.IntVar(&flagConfig.MaxLibvirtCalls, "max-libvirt-calls", flagConfig.MaxLibvirtCalls, "maximum of requests using the libvirt connection at once")
+	return rootCmd
+}
+
+func run(config Config) error {
+	// systemd stops the agent with SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	listener, err := net.Listen("tcp", config.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	// the connection outlives ctx, as the requests in flight keep using it until the server stops
+	connCtx, closeConn := context.WithCancel(context.Background())
+	conn := kvm.NewConnection(config.LibvirtURI, config.Pool, config.MaxLibvirtCalls)
+	connClosed := make(chan struct{})
+	go func() {
+		conn.Run(connCtx)
+		close(connClosed)
+	}()
+
+	// the recovery interceptor comes first, so that it catches the panics of the status interceptor too
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(recoveryInterceptor, statusInterceptor))
+	sa.RegisterStorageAgentServer(srv, &server{conn: conn, settingsDir: config.SettingsDir})
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(listener)
+	}()
+
+	log.Printf("KVM CSI Driver StorageAgent has been started on %s using the storage pool %s", config.Listen, config.Pool)
+
+	select {
+	case <-ctx.Done():
+		log.Print("KVM CSI Driver StorageAgent is stopping, waiting for the requests in progress")
+		srv.GracefulStop()
+	case err = <-serveErr:
+		err = fmt.Errorf("failed to serve: %w", err)
+	}
+
+	closeConn()
+	<-connClosed
+	log.Print("KVM CSI Driver StorageAgent has been stopped")
+	return err
+}
+
+func main() {
+	err := newRootCmd().Execute()
+	if err != nil {
+		os.Exit(1)
+	}
+}
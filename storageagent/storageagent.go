@@ -2,74 +2,1090 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/digitalocean/go-libvirt"
 	"github.com/onlineque/kvmCsiDriver/pkg/kvm"
 	sa "github.com/onlineque/kvmCsiDriver/storageagent_proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"log"
-	"net"
 	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
-const QCOWImagePath = "/var/lib/libvirt/images/%s.qcow2"
+// ImageVolumeName is the name of the volume of a qcow2 image in the storage pool
+const ImageVolumeName = "%s.qcow2"
+
+// RawImageVolumeName is the name of the volume of a raw image in the storage pool
+const RawImageVolumeName = "%s.raw"
+
+// SnapshotVolumeName is filled with the source image ID and the snapshot ID, the separator is one LVM
+// accepts in the names of logical volumes
+const SnapshotVolumeName = "%s" + snapshotSeparator + "%s.qcow2"
+
+const snapshotSeparator = "_snap_"
+
+// BaseVolumeName is the read-only base shared by a linked clone and its source, named after the clone
+const BaseVolumeName = "%s.base.qcow2"
 
 type server struct {
 	sa.UnimplementedStorageAgentServer
+	conn        *kvm.Connection
+	settingsDir string
+}
+
+func (s *server) CreateImage(ctx context.Context, req *sa.ImageRequest) (*sa.Image, error) {
+	k, release, err := s.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	options := imageOptionsFromRequest(req.Options)
+	imageName := imageVolumeName(req.ImageId, options.Format)
+	if req.SnapshotId != "" {
+		return s.restoreImage(k, req, imageName)
+	}
+
+	img, err := s.existingImage(k, req.ImageId, imageName, req.Size)
+	if err != nil || img != nil {
+		return img, err
+	}
+
+	volume, err := k.CreateVolume(imageName, req.Size, options)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating the image (%s) for the volume: %w", imageName, err)
+	}
+	err = s.saveCreatedSettings(req.ImageId, volume, req.IoTune, req.CacheMode)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("volume %s created", imageName)
+	return &sa.Image{
+		Success: true,
+		ImageId: req.ImageId,
+		Size:    volume.Capacity,
+	}, nil
+}
+
+// existingImage returns the image when it exists already, which makes repeated create requests succeed.
+// libvirt rounds the capacity up, e.g. to the extent size of LVM, so an image smaller than requested
+// can't be reused, while a bigger one can. A request for an image still being copied
+// is aborted, the image whose copy was interrupted is deleted to be copied again.
+func (s *server) existingImage(k *kvm.Kvm, imageID string, imageName string, size int64) (*sa.Image, error) {
+	if isCopying(imageName) {
+		return nil, status.Errorf(codes.Aborted, "image %s is being copied", imageID)
+	}
+	removed, err := s.removeInterruptedCopy(k, imageName)
+	if err != nil || removed {
+		return nil, err
+	}
+
+	volume, err := findImage(k, imageID)
+	if errors.Is(err, kvm.ErrImageNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if volume.Name != imageName {
+		return nil, status.Errorf(codes.AlreadyExists, "image %s exists already as the volume %s", imageID, volume.Name)
+	}
+	if volume.Capacity < size {
+		return nil, status.Errorf(codes.AlreadyExists, "image %s exists already with a smaller size (%d)", imageID, volume.Capacity)
+	}
+
+	log.Printf("volume %s exists already", imageName)
+	return &sa.Image{
+		Success: true,
+		ImageId: imageID,
+		Size:    volume.Capacity,
+	}, nil
+}
+
+func (s *server) restoreImage(k *kvm.Kvm, req *sa.ImageRequest, imageName string) (*sa.Image, error) {
+	_, snapshotName, err := findSnapshot(k, req.SnapshotId)
+	if err != nil {
+		return nil, err
+	}
+	if snapshotName == "" {
+		return nil, status.Errorf(codes.NotFound, "snapshot %s does not exist", req.SnapshotId)
+	}
+
+	snapshot, err := k.LookupVolume(snapshotName)
+	if err != nil {
+		return nil, err
+	}
+	if req.Size != 0 && req.Size < snapshot.Capacity {
+		return nil, status.Errorf(codes.OutOfRange, "requested size %d is smaller than the snapshot %s (%d)", req.Size, req.SnapshotId, snapshot.Capacity)
+	}
+	size := max(req.Size, snapshot.Capacity)
+
+	img, err := s.existingImage(k, req.ImageId, imageName, size)
+	if err != nil || img != nil {
+		return img, err
+	}
+
+	volume, err := s.copyVolume(k, snapshot, imageName, size, imageOptionsFromRequest(req.Options))
+	if err != nil {
+		return nil, fmt.Errorf("error while restoring the image (%s) from the snapshot %s: %w", imageName, req.SnapshotId, err)
+	}
+	err = s.saveCreatedSettings(req.ImageId, volume, req.IoTune, req.CacheMode)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("volume %s restored from the snapshot %s", imageName, req.SnapshotId)
+	return &sa.Image{
+		Success: true,
+		ImageId: req.ImageId,
+		Size:    volume.Capacity,
+	}, nil
+}
+
+// imageOptionsFromRequest converts the image options of a request, nil meaning the defaults
+func imageOptionsFromRequest(options *sa.ImageOptions) kvm.ImageOptions {
+	return kvm.ImageOptions{
+		Format:        options.GetFormat(),
+		Preallocation: options.GetPreallocation(),
+		ClusterSize:   options.GetClusterSize(),
+		LazyRefcounts: options.GetLazyRefcounts(),
+	}
+}
+
+func (s *server) DeleteImage(ctx context.Context, req *sa.ImageRequest) (*sa.Image, error) {
+	basesMutex.Lock()
+	defer basesMutex.Unlock()
+
+	k, release, err := s.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	for _, imageName := range imageVolumeNames(req.ImageId) {
+		err = k.DeleteVolume(imageName)
+		if err != nil && !errors.Is(err, kvm.ErrImageNotFound) {
+			return nil, err
+		}
+
+		err = os.Remove(s.copyMarkerPath(imageName))
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("error while removing the copy marker of the volume %s: %v", imageName, err)
+		}
+	}
+
+	err = os.Remove(s.settingsPath(req.ImageId))
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("error while removing the settings of the volume %s: %v", req.ImageId, err)
+	}
+	conditionsMutex.Lock()
+	delete(conditions, req.ImageId)
+	conditionsMutex.Unlock()
+
+	err = removeUnusedBases(k)
+	if err != nil {
+		log.Printf("error while removing unused linked clone bases: %v", err)
+	}
+
+	log.Printf("volume %s deleted", req.ImageId)
+	return &sa.Image{
+		Success: true,
+		ImageId: req.ImageId,
+	}, nil
+}
+
+// imageVolumeName returns the name of the volume of an image in the given format
+func imageVolumeName(imageID string, format string) string {
+	if format == kvm.RawFormat {
+		return fmt.Sprintf(RawImageVolumeName, imageID)
+	}
+	return fmt.Sprintf(ImageVolumeName, imageID)
+}
+
+// imageVolumeNames returns the names the volume of an image can have
+func imageVolumeNames(imageID string) []string {
+	return []string{fmt.Sprintf(ImageVolumeName, imageID), fmt.Sprintf(RawImageVolumeName, imageID)}
+}
+
+// findImage returns the volume of the image whatever its format, failing with kvm.ErrImageNotFound
+// when it does not exist
+func findImage(k *kvm.Kvm, imageID string) (kvm.Volume, error) {
+	for _, imageName := range imageVolumeNames(imageID) {
+		volume, err := k.LookupVolume(imageName)
+		if !errors.Is(err, kvm.ErrImageNotFound) {
+			return volume, err
+		}
+	}
+	return kvm.Volume{}, fmt.Errorf("%w: image %s", kvm.ErrImageNotFound, imageID)
+}
+
+// lookupImage returns the volume of the image, failing with NotFound when it does not exist
+func lookupImage(k *kvm.Kvm, imageID string) (kvm.Volume, error) {
+	volume, err := findImage(k, imageID)
+	if errors.Is(err, kvm.ErrImageNotFound) {
+		return kvm.Volume{}, status.Errorf(codes.NotFound, "image %s does not exist", imageID)
+	}
+	return volume, err
+}
+
+func (s *server) GetImage(ctx context.Context, req *sa.ImageRequest) (*sa.Image, error) {
+	k, release, err := s.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	volume, err := lookupImage(k, req.ImageId)
+	if err != nil {
+		return nil, err
+	}
+	if !req.WithStatus {
+		return &sa.Image{
+			Success: true,
+			ImageId: req.ImageId,
+			Size:    volume.Capacity,
+		}, nil
+	}
+
+	attachedImages, err := k.GetAttachedImages()
+	if err != nil {
+		return nil, err
+	}
+	domainNames := attachedImages[volume.Path]
+
+	// ControllerGetVolume asks for a single volume, so it gets the image checked right away
+	condition, err := s.imageCondition(k, req.ImageId, volume, len(domainNames) > 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sa.Image{
+		Success:          true,
+		ImageId:          req.ImageId,
+		Size:             volume.Capacity,
+		DomainNames:      domainNames,
+		Abnormal:         condition.abnormal,
+		ConditionMessage: condition.message,
+	}, nil
+}
+
+// imageCondition is the result of checking an image
+type imageCondition struct {
+	abnormal  bool
+	message   string
+	checkedAt time.Time
+}
+
+// conditionMaxAge is how long the condition of an image is reused by ListImages, which the health
+// monitor calls for all the volumes every minute, while checking a qcow2 image reads all its metadata
+const conditionMaxAge = 10 * time.Minute
+
+// conditions caches the last condition of each image
+var (
+	conditionsMutex sync.Mutex
+	conditions      = make(map[string]imageCondition)
+)
+
+// imageCondition returns the condition of the image, it is checked again once the last check is older
+// than maxAge. An image still being copied is not checked.
+func (s *server) imageCondition(k *kvm.Kvm, imageID string, volume kvm.Volume, attached bool, maxAge time.Duration) (imageCondition, error) {
+	if !s.isComplete(volume.Name) {
+		return imageCondition{message: "image is being copied"}, nil
+	}
+	conditionsMutex.Lock()
+	condition, ok := conditions[imageID]
+	conditionsMutex.Unlock()
+	if ok && time.Since(condition.checkedAt) < maxAge {
+		return condition, nil
+	}
+
+	format, err := s.imageFormat(imageID, volume)
+	if err != nil {
+		return imageCondition{}, err
+	}
+	abnormal, message, err := checkImageCondition(k, volume, format, attached)
+	if err != nil {
+		return imageCondition{}, err
+	}
+	condition = imageCondition{abnormal: abnormal, message: message, checkedAt: time.Now()}
+	conditionsMutex.Lock()
+	conditions[imageID] = condition
+	conditionsMutex.Unlock()
+	return condition, nil
+}
+
+// checkImageCondition reports whether the image is damaged, along with a message describing its condition
+func checkImageCondition(k *kvm.Kvm, volume kvm.Volume, format string, attached bool) (bool, string, error) {
+	// qemu-img checks the metadata of qcow2 images only, there is nothing to check in the other formats
+	if format != kvm.DefaultImageFormat {
+		return false, "volume is healthy", nil
+	}
+	if volume.BackingPath != "" {
+		if _, err := os.Stat(volume.BackingPath); os.IsNotExist(err) {
+			return true, fmt.Sprintf("backing file %s is missing", volume.BackingPath), nil
+		}
+	}
+
+	check, err := k.CheckImage(volume.Path, format)
+	if err != nil {
+		return false, "", err
+	}
+	if check.Corruptions > 0 {
+		return true, fmt.Sprintf("image has %d corruptions", check.Corruptions), nil
+	}
+	if check.CheckErrors > 0 {
+		return true, fmt.Sprintf("image check failed with %d errors", check.CheckErrors), nil
+	}
+	// a running domain does not flush the refcounts right away, so leaks are expected while attached
+	if check.Leaks > 0 && !attached {
+		return true, fmt.Sprintf("image has %d leaked clusters", check.Leaks), nil
+	}
+	return false, "volume is healthy", nil
+}
+
+// findVolumes returns the sorted names of the volumes of the pool matching the pattern
+func findVolumes(k *kvm.Kvm, pattern string) ([]string, error) {
+	names, err := k.ListVolumes()
+	if err != nil {
+		return nil, err
+	}
+	matches := []string{}
+	for _, name := range names {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// listImageIDs returns the sorted IDs of the volume images, leaving out snapshots and linked clone bases
+func listImageIDs(k *kvm.Kvm) ([]string, error) {
+	matches, err := findVolumes(k, fmt.Sprintf(ImageVolumeName, "*"))
+	if err != nil {
+		return nil, err
+	}
+	rawMatches, err := findVolumes(k, fmt.Sprintf(RawImageVolumeName, "*"))
+	if err != nil {
+		return nil, err
+	}
+	snapshotPattern := fmt.Sprintf(SnapshotVolumeName, "*", "*")
+	basePattern := fmt.Sprintf(BaseVolumeName, "*")
+
+	imageIDs := []string{}
+	for _, imageName := range matches {
+		if isSnapshot, _ := filepath.Match(snapshotPattern, imageName); isSnapshot {
+			continue
+		}
+		if isBase, _ := filepath.Match(basePattern, imageName); isBase {
+			continue
+		}
+		imageIDs = append(imageIDs, strings.TrimSuffix(imageName, ".qcow2"))
+	}
+	for _, imageName := range rawMatches {
+		imageIDs = append(imageIDs, strings.TrimSuffix(imageName, ".raw"))
+	}
+	sort.Strings(imageIDs)
+	return imageIDs, nil
+}
+
+func (s *server) ListImages(ctx context.Context, req *sa.ListImagesRequest) (*sa.ImageList, error) {
+	k, release, err := s.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	imageIDs, err := listImageIDs(k)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, nextToken, err := paginate(len(imageIDs), req.MaxEntries, req.StartingToken)
+	if err != nil {
+		return nil, err
+	}
+
+	attachedImages, err := k.GetAttachedImages()
+	if err != nil {
+		return nil, err
+	}
+
+	images := []*sa.Image{}
+	for _, imageID := range imageIDs[start:end] {
+		volume, err := findImage(k, imageID)
+		if err != nil {
+			return nil, err
+		}
+		domainNames := attachedImages[volume.Path]
+		condition, err := s.imageCondition(k, imageID, volume, len(domainNames) > 0, conditionMaxAge)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, &sa.Image{
+			Success:          true,
+			ImageId:          imageID,
+			Size:             volume.Capacity,
+			DomainNames:      domainNames,
+			Abnormal:         condition.abnormal,
+			ConditionMessage: condition.message,
+		})
+	}
+
+	return &sa.ImageList{
+		Images:    images,
+		NextToken: nextToken,
+	}, nil
+}
+
+func (s *server) CloneImage(ctx context.Context, req *sa.CloneRequest) (*sa.Image, error) {
+	if req.Linked {
+		basesMutex.Lock()
+		defer basesMutex.Unlock()
+	}
+	// the source is neither attached nor detached meanwhile, as that decides how it gets cloned
+	defer lockImage(req.SourceImageId)()
+
+	k, release, err := s.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	options := imageOptionsFromRequest(req.Options)
+	imageName := imageVolumeName(req.ImageId, options.Format)
+	source, err := lookupImage(k, req.SourceImageId)
+	if err != nil {
+		return nil, err
+	}
+	if req.Size != 0 && req.Size < source.Capacity {
+		return nil, status.Errorf(codes.OutOfRange, "requested size %d is smaller than the source volume %s (%d)", req.Size, req.SourceImageId, source.Capacity)
+	}
+	size := max(req.Size, source.Capacity)
+
+	img, err := s.existingImage(k, req.ImageId, imageName, size)
+	if err != nil || img != nil {
+		return img, err
+	}
+
+	sourceFormat, err := s.copyableFormat(req.SourceImageId, source)
+	if err != nil {
+		return nil, err
+	}
+	var volume kvm.Volume
+	if req.Linked {
+		// the overlays of a linked clone are always qcow2 images
+		if options.Format != "" && options.Format != kvm.DefaultImageFormat {
+			return nil, status.Errorf(codes.InvalidArgument, "linked clones can't be made in the %s format", options.Format)
+		}
+		if sourceFormat != kvm.DefaultImageFormat {
+			return nil, status.Errorf(codes.FailedPrecondition, "linked clones can't be made from %s images", sourceFormat)
+		}
+		volume, err = s.linkImage(k, req.ImageId, source, imageName, size)
+	} else {
+		volume, err = s.fullClone(k, source, imageName, size, options)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while cloning the image (%s) from the volume %s: %w", imageName, req.SourceImageId, err)
+	}
+	err = s.saveCreatedSettings(req.ImageId, volume, req.IoTune, req.CacheMode)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("volume %s cloned from the volume %s (linked: %t)", imageName, req.SourceImageId, req.Linked)
+	return &sa.Image{
+		Success: true,
+		ImageId: req.ImageId,
+		Size:    volume.Capacity,
+	}, nil
+}
+
+func (s *server) ResizeImage(ctx context.Context, req *sa.ResizeRequest) (*sa.Image, error) {
+	k, release, err := s.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	volume, err := lookupImage(k, req.ImageId)
+	if err != nil {
+		return nil, err
+	}
+	imageName := volume.Name
+	if req.Size <= volume.Capacity {
+		log.Printf("volume %s already has %d bytes, no resize needed", imageName, volume.Capacity)
+		return &sa.Image{
+			Success: true,
+			ImageId: req.ImageId,
+			Size:    volume.Capacity,
+		}, nil
+	}
+
+	domainName, deviceName, err := k.FindDomainBySource(volume.Path)
+	if err != nil {
+		return nil, err
+	}
+	if domainName != "" {
+		// the running domain holds the image open, so it has to be resized through libvirt
+		err = k.ResizeAttachedVolume(domainName, deviceName, req.Size)
+	} else {
+		err = k.ResizeVolume(imageName, req.Size)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while resizing the image (%s): %w", imageName, err)
+	}
+
+	log.Printf("volume %s resized to %d bytes", imageName, req.Size)
+	return &sa.Image{
+		Success: true,
+		ImageId: req.ImageId,
+		Size:    req.Size,
+	}, nil
+}
+
+func (s *server) GetCapacity(ctx context.Context, _ *sa.CapacityRequest) (*sa.Capacity, error) {
+	k, release, err := s.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	total, available, err := k.GetPoolCapacity()
+	if err != nil {
+		return nil, err
+	}
+
+	return &sa.Capacity{
+		AvailableBytes: available,
+		TotalBytes:     total,
+	}, nil
+}
+
+// settingsPath returns the file keeping the settings of a volume, which have to survive detaching it
+func (s *server) settingsPath(imageID string) string {
+	return filepath.Join(s.settingsDir, imageID+".json")
+}
+
+func (s *server) loadDiskSettings(imageID string) (kvm.DiskSettings, bool, error) {
+	var settings kvm.DiskSettings
+	data, err := os.ReadFile(s.settingsPath(imageID))
+	if os.IsNotExist(err) {
+		return settings, false, nil
+	}
+	if err != nil {
+		return settings, false, err
+	}
+	err = json.Unmarshal(data, &settings)
+	if err != nil {
+		return settings, false, fmt.Errorf("error unmarshalling the settings of the volume %s: %w", imageID, err)
+	}
+	return settings, true, nil
+}
+
+func diskSettingsFromRequest(ioTune *sa.IoTune, cacheMode string) kvm.DiskSettings {
+	return kvm.DiskSettings{
+		IoTune: kvm.IoTune{
+			TotalBytesSec: uint64(ioTune.GetTotalBytesSec()),
+			ReadBytesSec:  uint64(ioTune.GetReadBytesSec()),
+			WriteBytesSec: uint64(ioTune.GetWriteBytesSec()),
+			TotalIopsSec:  uint64(ioTune.GetTotalIopsSec()),
+			ReadIopsSec:   uint64(ioTune.GetReadIopsSec()),
+			WriteIopsSec:  uint64(ioTune.GetWriteIopsSec()),
+		},
+		Cache: cacheMode,
+	}
+}
+
+// saveCreatedSettings saves the settings the image was created with. The format of the image is only
+// taken from its contents now, before any guest wrote to it.
+func (s *server) saveCreatedSettings(imageID string, volume kvm.Volume, ioTune *sa.IoTune, cacheMode string) error {
+	settings := diskSettingsFromRequest(ioTune, cacheMode)
+	settings.Format = volume.DetectedFormat()
+	err := s.saveDiskSettings(imageID, settings)
+	if err != nil {
+		return fmt.Errorf("error while saving the settings of the volume %s: %w", imageID, err)
+	}
+	return nil
+}
+
+// savedFormat returns the format the image was created in. The images created before their format
+// was saved have it in their names, the volumes of LVM and disk pools hold raw data.
+func savedFormat(settings kvm.DiskSettings, volume kvm.Volume) string {
+	switch {
+	case settings.Format != "":
+		return settings.Format
+	case volume.Block, strings.HasSuffix(volume.Name, ".raw"):
+		return kvm.RawFormat
+	default:
+		return kvm.DefaultImageFormat
+	}
 }
 
-func (s *server) CreateImage(_ context.Context, req *sa.ImageRequest) (*sa.Image, error) {
-	imageName := fmt.Sprintf(QCOWImagePath, req.ImageId)
-	k := kvm.Kvm{}
-	err := k.CreateVolume(imageName, req.Size)
+// imageFormat returns the format the image was created in, which the contents of the image can't change
+func (s *server) imageFormat(imageID string, volume kvm.Volume) (string, error) {
+	settings, _, err := s.loadDiskSettings(imageID)
 	if err != nil {
-		return nil, fmt.Errorf("error while creating the QCOW2 image (%s) for the volume: %w", imageName, err)
+		return "", err
 	}
+	return savedFormat(settings, volume), nil
+}
 
-	log.Printf("volume %s.qcow2 created", req.ImageId)
-	return &sa.Image{
-		Success: true,
-		ImageId: req.ImageId,
-	}, nil
+// copyableFormat returns the format of the image to be copied. libvirt reads the source of a copy in the
+// format it detects, so a raw image whose guest made it look like qcow2 would be copied along with
+// the backing file named by the guest.
+func (s *server) copyableFormat(imageID string, volume kvm.Volume) (string, error) {
+	format, err := s.imageFormat(imageID, volume)
+	if err != nil {
+		return "", err
+	}
+	if volume.DetectedFormat() != format {
+		return "", status.Errorf(codes.FailedPrecondition, "image %s was created as %s, but its contents look like %s", imageID, format, volume.DetectedFormat())
+	}
+	return format, nil
+}
+
+func (s *server) saveDiskSettings(imageID string, settings kvm.DiskSettings) error {
+	settingsName := s.settingsPath(imageID)
+	err := os.MkdirAll(filepath.Dir(settingsName), 0755)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(settingsName, data, 0644)
+}
+
+// updateDiskSettings changes the settings named by the update mask of the request, keeping the others
+func updateDiskSettings(settings kvm.DiskSettings, req *sa.ModifyRequest) (kvm.DiskSettings, error) {
+	requested := diskSettingsFromRequest(req.IoTune, req.CacheMode)
+	for _, name := range req.UpdateMask {
+		switch name {
+		case "totalBytesSec":
+			settings.IoTune.TotalBytesSec = requested.IoTune.TotalBytesSec
+		case "readBytesSec":
+			settings.IoTune.ReadBytesSec = requested.IoTune.ReadBytesSec
+		case "writeBytesSec":
+			settings.IoTune.WriteBytesSec = requested.IoTune.WriteBytesSec
+		case "totalIopsSec":
+			settings.IoTune.TotalIopsSec = requested.IoTune.TotalIopsSec
+		case "readIopsSec":
+			settings.IoTune.ReadIopsSec = requested.IoTune.ReadIopsSec
+		case "writeIopsSec":
+			settings.IoTune.WriteIopsSec = requested.IoTune.WriteIopsSec
+		case "cacheMode":
+			settings.Cache = requested.Cache
+		default:
+			return settings, status.Errorf(codes.InvalidArgument, "setting %s can't be modified", name)
+		}
+	}
+
+	// libvirt refuses a total limit combined with the read or write one, which the kept limits could make
+	ioTune := settings.IoTune
+	if ioTune.TotalBytesSec > 0 && (ioTune.ReadBytesSec > 0 || ioTune.WriteBytesSec > 0) ||
+		ioTune.TotalIopsSec > 0 && (ioTune.ReadIopsSec > 0 || ioTune.WriteIopsSec > 0) {
+		return settings, status.Errorf(codes.InvalidArgument, "the total limits can't be combined with the read or write ones: %+v", ioTune)
+	}
+	return settings, nil
 }
 
-func (s *server) DeleteImage(_ context.Context, req *sa.ImageRequest) (*sa.Image, error) {
-	err := os.Remove(fmt.Sprintf(QCOWImagePath, req.ImageId))
+func (s *server) ModifyImage(ctx context.Context, req *sa.ModifyRequest) (*sa.Image, error) {
+	k, release, err := s.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	volume, err := lookupImage(k, req.ImageId)
+	if err != nil {
+		return nil, err
+	}
+	imageName := volume.Path
+
+	domainName, deviceName, err := k.FindDefinedDomainBySource(imageName)
+	if err != nil {
+		return nil, err
+	}
+
+	// the volumes created before the settings were saved along with them have their settings on the disk only
+	settings, found, err := s.loadDiskSettings(req.ImageId)
+	if err != nil {
+		return nil, err
+	}
+	if !found && domainName != "" {
+		settings, err = k.GetDiskSettings(domainName, deviceName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	settings, err = updateDiskSettings(settings, req)
 	if err != nil {
 		return nil, err
 	}
+	settings.Format = savedFormat(settings, volume)
+
+	if domainName != "" {
+		err = k.SetIoTune(domainName, deviceName, settings.IoTune)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("I/O limits of the volume %s applied to the domain %s", imageName, domainName)
+	}
+
+	// the cache mode can't be changed on a live disk, it takes effect when the volume is attached again
+	err = s.saveDiskSettings(req.ImageId, settings)
+	if err != nil {
+		return nil, fmt.Errorf("error while saving the settings of the volume %s: %w", req.ImageId, err)
+	}
 
-	log.Printf("volume %s.qcow2 deleted", req.ImageId)
+	log.Printf("volume %s modified", volume.Name)
 	return &sa.Image{
 		Success: true,
 		ImageId: req.ImageId,
 	}, nil
 }
 
-func (s *server) AttachVolume(_ context.Context, req *sa.VolumeRequest) (*sa.Volume, error) {
+// basesMutex is held while linking an image and while deleting images along with the unused bases,
+// so that a base is never seen before the source overlay backed by it exists. It is taken before
+// the libvirt slot, the same as the domain locks.
+var basesMutex sync.Mutex
+
+// fullClone copies the source image, the disk of a running domain gets mirrored into the copy
+func (s *server) fullClone(k *kvm.Kvm, source kvm.Volume, imageName string, size int64, options kvm.ImageOptions) (kvm.Volume, error) {
+	domainName, deviceName, err := k.FindDomainBySource(source.Path)
+	if err != nil {
+		return kvm.Volume{}, err
+	}
+	if domainName != "" {
+		return s.copyAttachedVolume(k, domainName, deviceName, source, imageName, size, options)
+	}
+	return s.copyVolume(k, source, imageName, size, options)
+}
+
+// linkImage turns the source image into a read-only base and puts thin overlays for both
+// the source and the clone on top of it, as a base image must never be written to again.
+// A running domain keeps the source open, so the clone gets a base of its own mirrored from
+// the disk of the domain instead.
+func (s *server) linkImage(k *kvm.Kvm, imageID string, source kvm.Volume, imageName string, size int64) (kvm.Volume, error) {
+	if source.Block {
+		return kvm.Volume{}, status.Error(codes.FailedPrecondition, "linked clones can't be made from block volumes")
+	}
+	baseName := fmt.Sprintf(BaseVolumeName, imageID)
+	domainName, deviceName, err := k.FindDomainBySource(source.Path)
+	if err != nil {
+		return kvm.Volume{}, err
+	}
+	if domainName != "" {
+		_, err = s.removeInterruptedCopy(k, baseName)
+		if err != nil {
+			return kvm.Volume{}, err
+		}
+		base, err := s.copyAttachedVolume(k, domainName, deviceName, source, baseName, source.Capacity, kvm.ImageOptions{Format: kvm.DefaultImageFormat})
+		if err != nil {
+			return kvm.Volume{}, err
+		}
+		return k.CreateLinkedVolume(base, imageName, size)
+	}
+
+	// libvirt can't rename volumes, so the file is renamed behind its back and the pool gets refreshed
+	base := source
+	base.Name = baseName
+	base.Path = filepath.Join(filepath.Dir(source.Path), base.Name)
+	err = os.Rename(source.Path, base.Path)
+	if err != nil {
+		return kvm.Volume{}, err
+	}
+	err = k.RefreshPool()
+	if err == nil {
+		_, err = k.CreateLinkedVolume(base, source.Name, source.Capacity)
+	}
+	if err != nil {
+		_ = os.Rename(base.Path, source.Path)
+		if refreshErr := k.RefreshPool(); refreshErr != nil {
+			log.Printf("error while refreshing the storage pool: %v", refreshErr)
+		}
+		return kvm.Volume{}, err
+	}
+	return k.CreateLinkedVolume(base, imageName, size)
+}
+
+// removeUnusedBases deletes the linked clone bases which no image is backed by anymore
+func removeUnusedBases(k *kvm.Kvm) error {
+	for {
+		// the pattern of the images matches the bases as well, which may be backed by other bases
+		images, err := findVolumes(k, fmt.Sprintf(ImageVolumeName, "*"))
+		if err != nil {
+			return err
+		}
+		usedBases := make(map[string]bool)
+		for _, image := range images {
+			volume, err := k.LookupVolume(image)
+			if err != nil {
+				return err
+			}
+			if volume.BackingPath != "" {
+				usedBases[volume.BackingPath] = true
+			}
+		}
+
+		bases, err := findVolumes(k, fmt.Sprintf(BaseVolumeName, "*"))
+		if err != nil {
+			return err
+		}
+		removed := false
+		for _, baseName := range bases {
+			base, err := k.LookupVolume(baseName)
+			if err != nil {
+				return err
+			}
+			if usedBases[base.Path] {
+				continue
+			}
+			err = k.DeleteVolume(baseName)
+			if err != nil {
+				return err
+			}
+			log.Printf("unused linked clone base %s deleted", baseName)
+			removed = true
+		}
+		// removing a base may release the base it was backed by
+		if !removed {
+			return nil
+		}
+	}
+}
+
+// lockMap holds a mutex for each key in use, the mutex is dropped once nobody waits for it anymore
+type lockMap struct {
+	mutex sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	users int
+}
+
+// lock locks the key and returns the function unlocking it
+func (m *lockMap) lock(key string) func() {
+	m.mutex.Lock()
+	l, ok := m.locks[key]
+	if !ok {
+		l = &keyLock{}
+		m.locks[key] = l
+	}
+	l.users++
+	m.mutex.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		m.mutex.Lock()
+		l.users--
+		if l.users == 0 {
+			delete(m.locks, key)
+		}
+		m.mutex.Unlock()
+	}
+}
+
+// domainLocks serialize attaching and detaching the disks of each domain, so that no two disks
+// get the same free device slot
+var domainLocks = &lockMap{locks: make(map[string]*keyLock)}
+
+// imageLocks serialize attaching and detaching each image with copying it or turning it into a linked
+// clone base, so that no domain starts using the image in the middle of that. An image lock is always
+// taken after the domain lock and basesMutex.
+var imageLocks = &lockMap{locks: make(map[string]*keyLock)}
+
+// copies holds the names of the volumes being copied, a volume is incomplete until its copy is done
+var (
+	copiesMutex sync.Mutex
+	copies      = make(map[string]bool)
+)
+
+// copyMarkerPath returns the file marking the volume as incomplete, which survives a crash of the agent
+func (s *server) copyMarkerPath(name string) string {
+	return filepath.Join(s.settingsDir, name+".copying")
+}
+
+// copyVolume copies the source into a new volume of the pool, marking the volume as incomplete
+// until the copy is done
+func (s *server) copyVolume(k *kvm.Kvm, source kvm.Volume, name string, size int64, options kvm.ImageOptions) (kvm.Volume, error) {
+	return s.trackCopy(name, func() (kvm.Volume, error) {
+		return k.CopyVolume(source, name, size, options)
+	})
+}
+
+// copyAttachedVolume copies the source, which is the disk of the running domain, into a new volume
+// of the pool, marking the volume as incomplete until the copy is done
+func (s *server) copyAttachedVolume(k *kvm.Kvm, domainName string, deviceName string, source kvm.Volume, name string, size int64, options kvm.ImageOptions) (kvm.Volume, error) {
+	return s.trackCopy(name, func() (kvm.Volume, error) {
+		return k.CopyAttachedVolume(domainName, deviceName, source, name, size, options)
+	})
+}
+
+// trackCopy runs the copy creating the volume, which is marked as incomplete until the copy is done
+func (s *server) trackCopy(name string, copyVolume func() (kvm.Volume, error)) (kvm.Volume, error) {
+	copiesMutex.Lock()
+	if copies[name] {
+		copiesMutex.Unlock()
+		return kvm.Volume{}, status.Errorf(codes.Aborted, "volume %s is being copied already", name)
+	}
+	copies[name] = true
+	copiesMutex.Unlock()
+	defer func() {
+		copiesMutex.Lock()
+		delete(copies, name)
+		copiesMutex.Unlock()
+	}()
+
+	marker := s.copyMarkerPath(name)
+	err := os.MkdirAll(filepath.Dir(marker), 0755)
+	if err != nil {
+		return kvm.Volume{}, err
+	}
+	err = os.WriteFile(marker, nil, 0644)
+	if err != nil {
+		return kvm.Volume{}, err
+	}
+	volume, err := copyVolume()
+	if err != nil {
+		// the incomplete volume is removed by libvirt or the kvm package
+		_ = os.Remove(marker)
+		return kvm.Volume{}, err
+	}
+	return volume, os.Remove(marker)
+}
+
+// isCopying reports whether the volume is being copied right now
+func isCopying(name string) bool {
+	copiesMutex.Lock()
+	defer copiesMutex.Unlock()
+	return copies[name]
+}
+
+// isComplete reports whether the copy of the volume is done, the volumes which are not copies are
+// always complete
+func (s *server) isComplete(name string) bool {
+	if isCopying(name) {
+		return false
+	}
+	_, err := os.Stat(s.copyMarkerPath(name))
+	return os.IsNotExist(err)
+}
+
+// removeInterruptedCopy deletes the volume whose copy was interrupted by a crash of the agent, so that it
+// gets copied again. It reports whether the volume was deleted.
+func (s *server) removeInterruptedCopy(k *kvm.Kvm, name string) (bool, error) {
+	marker := s.copyMarkerPath(name)
+	if isCopying(name) {
+		return false, nil
+	}
+	if _, err := os.Stat(marker); os.IsNotExist(err) {
+		return false, nil
+	}
+	log.Printf("copy of the volume %s was interrupted, deleting it", name)
+	err := k.DeleteVolume(name)
+	if err != nil && !errors.Is(err, kvm.ErrImageNotFound) {
+		return false, err
+	}
+	return true, os.Remove(marker)
+}
+
+// lockDomain locks the disks of the domain and returns the function unlocking them
+func lockDomain(domainName string) func() {
+	return domainLocks.lock(domainName)
+}
+
+// lockImage locks attaching, detaching and copying the image and returns the function unlocking it
+func lockImage(imageID string) func() {
+	return imageLocks.lock(imageID)
+}
+
+func (s *server) AttachVolume(ctx context.Context, req *sa.VolumeRequest) (*sa.Volume, error) {
 	imageID := req.ImageId
-	imageName := fmt.Sprintf(QCOWImagePath, imageID)
 	targetPath := req.TargetPath
 	domainName := req.DomainName
 
-	log.Printf("mounting %s on %s:%s ...", imageName, domainName, targetPath)
+	// the domain lock is taken before the connection slot, so that the requests waiting for a busy
+	// domain do not hold the slots needed by the requests for the other domains
+	defer lockDomain(domainName)()
+	defer lockImage(imageID)()
+
+	k, release, err := s.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 
-	k := kvm.Kvm{
-		URI: string(libvirt.QEMUSystem),
+	volume, err := lookupImage(k, imageID)
+	if err != nil {
+		return nil, err
 	}
+	imageName := volume.Path
 
-	err := k.Connect()
+	log.Printf("mounting %s on %s:%s ...", imageName, domainName, targetPath)
+
+	attachedDomain, attachedDevice, err := k.FindDefinedDomainBySource(imageName)
 	if err != nil {
 		return nil, err
 	}
-	defer k.Disconnect()
+	if attachedDomain == domainName {
+		readonly, err := k.IsDiskReadOnly(domainName, attachedDevice)
+		if err != nil {
+			return nil, err
+		}
+		if readonly != req.Readonly {
+			return nil, status.Errorf(codes.AlreadyExists, "image %s is attached to domain %s with readonly %t", imageID, domainName, readonly)
+		}
+		log.Printf("volume %s is attached to domain %s as %s already", imageName, domainName, attachedDevice)
+		return &sa.Volume{
+			ImageId: imageID,
+			Success: true,
+			Device:  attachedDevice,
+			Serial:  kvm.DiskSerial(imageID),
+		}, nil
+	}
+	if attachedDomain != "" {
+		return nil, status.Errorf(codes.FailedPrecondition, "image %s is attached to domain %s", imageID, attachedDomain)
+	}
 
-	nextDeviceName, err := k.FindNextUsableDeviceName(domainName)
+	// the settings changed by ModifyImage take precedence over the ones the volume was created with
+	settings, found, err := s.loadDiskSettings(imageID)
 	if err != nil {
-		return nil, fmt.Errorf("error looking up next free device name: %w", err)
+		return nil, err
+	}
+	if !found {
+		settings = diskSettingsFromRequest(req.IoTune, req.CacheMode)
+	}
+	// the disk is attached in the format the image was created in, so that a guest can't turn its raw
+	// disk into a qcow2 image backed by a file of its choice
+	settings.Format = savedFormat(settings, volume)
+
+	// a qcow2 image names its backing file itself, only the linked clone bases of the pool are trusted,
+	// so that the host never opens any other file through the header of an image
+	if settings.Format == kvm.DefaultImageFormat && volume.BackingPath != "" {
+		isBase, _ := filepath.Match(fmt.Sprintf(BaseVolumeName, "*"), filepath.Base(volume.BackingPath))
+		if !isBase || filepath.Dir(volume.BackingPath) != filepath.Dir(volume.Path) {
+			return nil, status.Errorf(codes.FailedPrecondition, "image %s is backed by %s, which is not a linked clone base", imageID, volume.BackingPath)
+		}
 	}
 
-	err = k.AttachVolumeToDomain(domainName, imageName, nextDeviceName)
+	serial := kvm.DiskSerial(imageID)
+	nextDeviceName, err := k.AttachVolumeToDomain(domainName, volume, serial, settings, req.Readonly)
 	if err != nil {
 		return nil, err
 	}
@@ -79,31 +1095,67 @@ func (s *server) AttachVolume(_ context.Context, req *sa.VolumeRequest) (*sa.Vol
 		ImageId: imageID,
 		Success: true,
 		Device:  nextDeviceName,
+		Serial:  serial,
 	}, nil
 }
 
-func (s *server) DetachVolume(_ context.Context, req *sa.VolumeRequest) (*sa.Volume, error) {
+func (s *server) DetachVolume(ctx context.Context, req *sa.VolumeRequest) (*sa.Volume, error) {
 	imageID := req.ImageId
-	imageName := fmt.Sprintf(QCOWImagePath, imageID)
 	targetPath := req.TargetPath
 	domainName := req.DomainName
 
-	log.Printf("unmounting %s from %s:%s ...", imageName, domainName, targetPath)
+	if domainName == "" {
+		// an empty domain name detaches the image from whichever domain it is attached to
+		attachedDomain, err := s.findAttachedDomain(ctx, imageID)
+		if err != nil {
+			return nil, err
+		}
+		if attachedDomain == "" {
+			log.Printf("volume %s is not attached to any domain", imageID)
+			return &sa.Volume{
+				ImageId: imageID,
+				Success: true,
+			}, nil
+		}
+		domainName = attachedDomain
+	}
+
+	defer lockDomain(domainName)()
+	defer lockImage(imageID)()
 
-	k := kvm.Kvm{
-		URI: string(libvirt.QEMUSystem),
+	k, release, err := s.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
-	err := k.Connect()
+	volume, err := findImage(k, imageID)
+	if errors.Is(err, kvm.ErrImageNotFound) {
+		log.Printf("volume %s does not exist, nothing to detach", imageID)
+		return &sa.Volume{
+			ImageId: imageID,
+			Success: true,
+		}, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	defer k.Disconnect()
+	imageName := volume.Path
+
+	log.Printf("unmounting %s from %s:%s ...", imageName, domainName, targetPath)
 
-	deviceName, err := k.GetDeviceNameBySource(domainName, imageName)
+	attachedDomain, deviceName, err := k.FindDefinedDomainBySource(imageName)
 	if err != nil {
-		return nil, fmt.Errorf("error getting the device name for the image: %w", err)
+		return nil, err
 	}
+	if attachedDomain != domainName {
+		log.Printf("volume %s is not attached to domain %s", imageName, domainName)
+		return &sa.Volume{
+			ImageId: imageID,
+			Success: true,
+		}, nil
+	}
+
 	err = k.DetachVolumeFromDomain(domainName, imageName, deviceName)
 	if err != nil {
 		return nil, err
@@ -116,29 +1168,252 @@ func (s *server) DetachVolume(_ context.Context, req *sa.VolumeRequest) (*sa.Vol
 	}, nil
 }
 
-func main() {
-	ctx := context.TODO()
+// findAttachedDomain returns the domain the image is attached to, or an empty string
+func (s *server) findAttachedDomain(ctx context.Context, imageID string) (string, error) {
+	k, release, err := s.conn.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	volume, err := findImage(k, imageID)
+	if errors.Is(err, kvm.ErrImageNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	domainName, _, err := k.FindDefinedDomainBySource(volume.Path)
+	return domainName, err
+}
+
+// findSnapshot returns the source image ID and the volume of the snapshot, or empty strings if it does not exist
+func findSnapshot(k *kvm.Kvm, snapshotID string) (string, string, error) {
+	matches, err := findVolumes(k, fmt.Sprintf(SnapshotVolumeName, "*", snapshotID))
+	if err != nil {
+		return "", "", err
+	}
+	if len(matches) == 0 {
+		return "", "", nil
+	}
+	imageID, _, _ := strings.Cut(strings.TrimSuffix(matches[0], ".qcow2"), snapshotSeparator)
+	return imageID, matches[0], nil
+}
 
-	listener, err := net.Listen("tcp", ":7003")
+func (s *server) snapshotFromVolume(k *kvm.Kvm, imageID string, snapshotID string, snapshotName string) (*sa.Snapshot, error) {
+	volume, err := k.LookupVolume(snapshotName)
+	if err != nil {
+		return nil, err
+	}
+	// libvirt does not keep the creation time, the snapshot is never written to after its creation
+	fileInfo, err := os.Stat(volume.Path)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		return nil, err
+	}
+	return &sa.Snapshot{
+		Success:      true,
+		SnapshotId:   snapshotID,
+		ImageId:      imageID,
+		Size:         volume.Capacity,
+		CreationTime: fileInfo.ModTime().Unix(),
+		ReadyToUse:   s.isComplete(snapshotName),
+	}, nil
+}
+
+// paginate returns the bounds of the requested page of a listing and the token of the next page
+func paginate(total int, maxEntries int32, startingToken string) (int, int, string, error) {
+	if maxEntries < 0 {
+		return 0, 0, "", status.Errorf(codes.InvalidArgument, "invalid maximum of entries %d", maxEntries)
+	}
+	start := 0
+	if startingToken != "" {
+		var err error
+		start, err = strconv.Atoi(startingToken)
+		if err != nil || start < 0 || start > total {
+			return 0, 0, "", status.Errorf(codes.Aborted, "invalid starting token %s", startingToken)
+		}
 	}
+	end := total
+	if maxEntries > 0 && start+int(maxEntries) < end {
+		end = start + int(maxEntries)
+	}
+
+	nextToken := ""
+	if end < total {
+		nextToken = strconv.Itoa(end)
+	}
+	return start, end, nextToken, nil
+}
 
-	defer listener.Close()
+func (s *server) CreateSnapshot(ctx context.Context, req *sa.SnapshotRequest) (*sa.Snapshot, error) {
+	// the image is neither attached nor detached while it is being copied, as that decides how it gets copied
+	defer lockImage(req.ImageId)()
 
-	srv := grpc.NewServer()
-	sa.RegisterStorageAgentServer(srv, &server{})
+	k, release, err := s.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 
-	go func() {
-		err = srv.Serve(listener)
+	imageID, snapshotName, err := findSnapshot(k, req.SnapshotId)
+	if err != nil {
+		return nil, err
+	}
+	if snapshotName != "" {
+		if imageID != req.ImageId {
+			return nil, status.Errorf(codes.AlreadyExists, "snapshot %s already exists for the image %s", req.SnapshotId, imageID)
+		}
+		removed, err := s.removeInterruptedCopy(k, snapshotName)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
-	}()
+		if !removed {
+			// a snapshot still being copied is reported as not ready to use, the snapshotter asks again
+			log.Printf("snapshot %s of the volume %s already exists", req.SnapshotId, imageID)
+			return s.snapshotFromVolume(k, imageID, req.SnapshotId, snapshotName)
+		}
+	}
+
+	volume, err := lookupImage(k, req.ImageId)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.copyableFormat(req.ImageId, volume)
+	if err != nil {
+		return nil, err
+	}
+
+	// the snapshot is a standalone copy of the image, so it survives deleting the source volume
+	snapshotName = fmt.Sprintf(SnapshotVolumeName, req.ImageId, req.SnapshotId)
+	options := kvm.ImageOptions{Format: kvm.DefaultImageFormat}
+	domainName, deviceName, err := k.FindDomainBySource(volume.Path)
+	if err != nil {
+		return nil, err
+	}
+	if domainName != "" {
+		// a running domain keeps writing to the image, so its disk gets mirrored into the snapshot by QEMU
+		_, err = s.copyAttachedVolume(k, domainName, deviceName, volume, snapshotName, volume.Capacity, options)
+	} else {
+		_, err = s.copyVolume(k, volume, snapshotName, volume.Capacity, options)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while creating the snapshot (%s) of the volume: %w", snapshotName, err)
+	}
+
+	log.Printf("snapshot %s of the volume %s created", req.SnapshotId, req.ImageId)
+	return s.snapshotFromVolume(k, req.ImageId, req.SnapshotId, snapshotName)
+}
+
+func (s *server) DeleteSnapshot(ctx context.Context, req *sa.SnapshotRequest) (*sa.Snapshot, error) {
+	k, release, err := s.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	imageID, snapshotName, err := findSnapshot(k, req.SnapshotId)
+	if err != nil {
+		return nil, err
+	}
+	if snapshotName == "" {
+		log.Printf("snapshot %s does not exist, nothing to delete", req.SnapshotId)
+		return &sa.Snapshot{
+			Success:    true,
+			SnapshotId: req.SnapshotId,
+		}, nil
+	}
+
+	err = k.DeleteVolume(snapshotName)
+	if err != nil {
+		return nil, err
+	}
+	err = os.Remove(s.copyMarkerPath(snapshotName))
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("error while removing the copy marker of the snapshot %s: %v", req.SnapshotId, err)
+	}
 
-	log.Print("KVM CSI Driver StorageAgent has been started")
+	log.Printf("snapshot %s of the volume %s deleted", req.SnapshotId, imageID)
+	return &sa.Snapshot{
+		Success:    true,
+		SnapshotId: req.SnapshotId,
+		ImageId:    imageID,
+	}, nil
+}
+
+func (s *server) ListSnapshots(ctx context.Context, req *sa.ListSnapshotsRequest) (*sa.SnapshotList, error) {
+	k, release, err := s.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	imageID := req.ImageId
+	if imageID == "" {
+		imageID = "*"
+	}
+	snapshotID := req.SnapshotId
+	if snapshotID == "" {
+		snapshotID = "*"
+	}
+	matches, err := findVolumes(k, fmt.Sprintf(SnapshotVolumeName, imageID, snapshotID))
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, nextToken, err := paginate(len(matches), req.MaxEntries, req.StartingToken)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []*sa.Snapshot{}
+	for _, snapshotName := range matches[start:end] {
+		imageID, snapshotID, _ := strings.Cut(strings.TrimSuffix(snapshotName, ".qcow2"), snapshotSeparator)
+		snapshot, err := s.snapshotFromVolume(k, imageID, snapshotID, snapshotName)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return &sa.SnapshotList{
+		Snapshots: snapshots,
+		NextToken: nextToken,
+	}, nil
+}
 
-	<-ctx.Done()
-	srv.GracefulStop()
-	log.Print("KVM CSI Driver StorageAgent has been stopped")
+// statusInterceptor gives the errors of the kvm package their gRPC status codes,
+// errors carrying a status already are passed through
+func statusInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err == nil {
+		return resp, nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return resp, err
+	}
+
+	code := codes.Internal
+	switch {
+	case errors.Is(err, kvm.ErrDomainNotFound), errors.Is(err, kvm.ErrImageNotFound):
+		code = codes.NotFound
+	case errors.Is(err, kvm.ErrNoFreeDevice):
+		code = codes.ResourceExhausted
+	case errors.Is(err, kvm.ErrDomainNotRunning):
+		code = codes.FailedPrecondition
+	case errors.Is(err, kvm.ErrLibvirtUnavailable):
+		code = codes.Unavailable
+	}
+	return resp, status.Error(code, err.Error())
+}
+
+// recoveryInterceptor turns a panic while handling a request into an INTERNAL error, so that it does
+// not take down the agent along with the requests of all the other nodes
+func recoveryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("panic while handling %s: %v\n%s", info.FullMethod, r, debug.Stack())
+			resp, err = nil, status.Errorf(codes.Internal, "panic while handling %s: %v", info.FullMethod, r)
+		}
+	}()
+	return handler(ctx, req)
 }
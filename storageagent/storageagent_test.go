@@ -0,0 +1,49 @@
+package main
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name          string
+		total         int
+		maxEntries    int32
+		startingToken string
+		start         int
+		end           int
+		nextToken     string
+		code          codes.Code
+	}{
+		{name: "all entries", total: 5, start: 0, end: 5},
+		{name: "first page", total: 5, maxEntries: 2, start: 0, end: 2, nextToken: "2"},
+		{name: "middle page", total: 5, maxEntries: 2, startingToken: "2", start: 2, end: 4, nextToken: "4"},
+		{name: "last page", total: 5, maxEntries: 2, startingToken: "4", start: 4, end: 5},
+		{name: "page ending at the total", total: 4, maxEntries: 2, startingToken: "2", start: 2, end: 4},
+		{name: "token at the total", total: 5, startingToken: "5", start: 5, end: 5},
+		{name: "no entries", total: 0, maxEntries: 2, start: 0, end: 0},
+		{name: "token greater than the total", total: 5, startingToken: "6", code: codes.Aborted},
+		{name: "negative token", total: 5, startingToken: "-1", code: codes.Aborted},
+		{name: "token not a number", total: 5, startingToken: "abc", code: codes.Aborted},
+		{name: "negative maximum of entries", total: 5, maxEntries: -1, code: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, nextToken, err := paginate(tt.total, tt.maxEntries, tt.startingToken)
+			if tt.code != codes.OK {
+				if status.Code(err) != tt.code {
+					t.Fatalf("expected the code %s, got the error %v", tt.code, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if start != tt.start || end != tt.end || nextToken != tt.nextToken {
+				t.Errorf("got (%d, %d, %q), expected (%d, %d, %q)", start, end, nextToken, tt.start, tt.end, tt.nextToken)
+			}
+		})
+	}
+}
@@ -24,8 +24,17 @@ const _ = grpc.SupportPackageIsVersion7
 type StorageAgentClient interface {
 	CreateImage(ctx context.Context, in *ImageRequest, opts ...grpc.CallOption) (*Image, error)
 	DeleteImage(ctx context.Context, in *ImageRequest, opts ...grpc.CallOption) (*Image, error)
+	GetImage(ctx context.Context, in *ImageRequest, opts ...grpc.CallOption) (*Image, error)
+	ListImages(ctx context.Context, in *ListImagesRequest, opts ...grpc.CallOption) (*ImageList, error)
 	AttachVolume(ctx context.Context, in *VolumeRequest, opts ...grpc.CallOption) (*Volume, error)
 	DetachVolume(ctx context.Context, in *VolumeRequest, opts ...grpc.CallOption) (*Volume, error)
+	CreateSnapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error)
+	DeleteSnapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error)
+	ListSnapshots(ctx context.Context, in *ListSnapshotsRequest, opts ...grpc.CallOption) (*SnapshotList, error)
+	CloneImage(ctx context.Context, in *CloneRequest, opts ...grpc.CallOption) (*Image, error)
+	ResizeImage(ctx context.Context, in *ResizeRequest, opts ...grpc.CallOption) (*Image, error)
+	GetCapacity(ctx context.Context, in *CapacityRequest, opts ...grpc.CallOption) (*Capacity, error)
+	ModifyImage(ctx context.Context, in *ModifyRequest, opts ...grpc.CallOption) (*Image, error)
 }
 
 type storageAgentClient struct {
@@ -54,6 +63,24 @@ func (c *storageAgentClient) DeleteImage(ctx context.Context, in *ImageRequest,
 	return out, nil
 }
 
+func (c *storageAgentClient) GetImage(ctx context.Context, in *ImageRequest, opts ...grpc.CallOption) (*Image, error) {
+	out := new(Image)
+	err := c.cc.Invoke(ctx, "/storageagent.v1.StorageAgent/GetImage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAgentClient) ListImages(ctx context.Context, in *ListImagesRequest, opts ...grpc.CallOption) (*ImageList, error) {
+	out := new(ImageList)
+	err := c.cc.Invoke(ctx, "/storageagent.v1.StorageAgent/ListImages", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *storageAgentClient) AttachVolume(ctx context.Context, in *VolumeRequest, opts ...grpc.CallOption) (*Volume, error) {
 	out := new(Volume)
 	err := c.cc.Invoke(ctx, "/storageagent.v1.StorageAgent/AttachVolume", in, out, opts...)
@@ -72,14 +99,86 @@ func (c *storageAgentClient) DetachVolume(ctx context.Context, in *VolumeRequest
 	return out, nil
 }
 
+func (c *storageAgentClient) CreateSnapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error) {
+	out := new(Snapshot)
+	err := c.cc.Invoke(ctx, "/storageagent.v1.StorageAgent/CreateSnapshot", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAgentClient) DeleteSnapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error) {
+	out := new(Snapshot)
+	err := c.cc.Invoke(ctx, "/storageagent.v1.StorageAgent/DeleteSnapshot", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAgentClient) ListSnapshots(ctx context.Context, in *ListSnapshotsRequest, opts ...grpc.CallOption) (*SnapshotList, error) {
+	out := new(SnapshotList)
+	err := c.cc.Invoke(ctx, "/storageagent.v1.StorageAgent/ListSnapshots", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAgentClient) CloneImage(ctx context.Context, in *CloneRequest, opts ...grpc.CallOption) (*Image, error) {
+	out := new(Image)
+	err := c.cc.Invoke(ctx, "/storageagent.v1.StorageAgent/CloneImage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAgentClient) ResizeImage(ctx context.Context, in *ResizeRequest, opts ...grpc.CallOption) (*Image, error) {
+	out := new(Image)
+	err := c.cc.Invoke(ctx, "/storageagent.v1.StorageAgent/ResizeImage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAgentClient) GetCapacity(ctx context.Context, in *CapacityRequest, opts ...grpc.CallOption) (*Capacity, error) {
+	out := new(Capacity)
+	err := c.cc.Invoke(ctx, "/storageagent.v1.StorageAgent/GetCapacity", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAgentClient) ModifyImage(ctx context.Context, in *ModifyRequest, opts ...grpc.CallOption) (*Image, error) {
+	out := new(Image)
+	err := c.cc.Invoke(ctx, "/storageagent.v1.StorageAgent/ModifyImage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // StorageAgentServer is the server API for StorageAgent service.
 // All implementations must embed UnimplementedStorageAgentServer
 // for forward compatibility
 type StorageAgentServer interface {
 	CreateImage(context.Context, *ImageRequest) (*Image, error)
 	DeleteImage(context.Context, *ImageRequest) (*Image, error)
+	GetImage(context.Context, *ImageRequest) (*Image, error)
+	ListImages(context.Context, *ListImagesRequest) (*ImageList, error)
 	AttachVolume(context.Context, *VolumeRequest) (*Volume, error)
 	DetachVolume(context.Context, *VolumeRequest) (*Volume, error)
+	CreateSnapshot(context.Context, *SnapshotRequest) (*Snapshot, error)
+	DeleteSnapshot(context.Context, *SnapshotRequest) (*Snapshot, error)
+	ListSnapshots(context.Context, *ListSnapshotsRequest) (*SnapshotList, error)
+	CloneImage(context.Context, *CloneRequest) (*Image, error)
+	ResizeImage(context.Context, *ResizeRequest) (*Image, error)
+	GetCapacity(context.Context, *CapacityRequest) (*Capacity, error)
+	ModifyImage(context.Context, *ModifyRequest) (*Image, error)
 	mustEmbedUnimplementedStorageAgentServer()
 }
 
@@ -93,12 +192,39 @@ func (UnimplementedStorageAgentServer) CreateImage(context.Context, *ImageReques
 func (UnimplementedStorageAgentServer) DeleteImage(context.Context, *ImageRequest) (*Image, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteImage not implemented")
 }
+func (UnimplementedStorageAgentServer) GetImage(context.Context, *ImageRequest) (*Image, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetImage not implemented")
+}
+func (UnimplementedStorageAgentServer) ListImages(context.Context, *ListImagesRequest) (*ImageList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListImages not implemented")
+}
 func (UnimplementedStorageAgentServer) AttachVolume(context.Context, *VolumeRequest) (*Volume, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AttachVolume not implemented")
 }
 func (UnimplementedStorageAgentServer) DetachVolume(context.Context, *VolumeRequest) (*Volume, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DetachVolume not implemented")
 }
+func (UnimplementedStorageAgentServer) CreateSnapshot(context.Context, *SnapshotRequest) (*Snapshot, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSnapshot not implemented")
+}
+func (UnimplementedStorageAgentServer) DeleteSnapshot(context.Context, *SnapshotRequest) (*Snapshot, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSnapshot not implemented")
+}
+func (UnimplementedStorageAgentServer) ListSnapshots(context.Context, *ListSnapshotsRequest) (*SnapshotList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSnapshots not implemented")
+}
+func (UnimplementedStorageAgentServer) CloneImage(context.Context, *CloneRequest) (*Image, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloneImage not implemented")
+}
+func (UnimplementedStorageAgentServer) ResizeImage(context.Context, *ResizeRequest) (*Image, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResizeImage not implemented")
+}
+func (UnimplementedStorageAgentServer) GetCapacity(context.Context, *CapacityRequest) (*Capacity, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCapacity not implemented")
+}
+func (UnimplementedStorageAgentServer) ModifyImage(context.Context, *ModifyRequest) (*Image, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ModifyImage not implemented")
+}
 func (UnimplementedStorageAgentServer) mustEmbedUnimplementedStorageAgentServer() {}
 
 // UnsafeStorageAgentServer may be embedded to opt out of forward compatibility for this service.
@@ -148,6 +274,42 @@ func _StorageAgent_DeleteImage_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StorageAgent_GetImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAgentServer).GetImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/storageagent.v1.StorageAgent/GetImage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAgentServer).GetImage(ctx, req.(*ImageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAgent_ListImages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListImagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAgentServer).ListImages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/storageagent.v1.StorageAgent/ListImages",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAgentServer).ListImages(ctx, req.(*ListImagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _StorageAgent_AttachVolume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(VolumeRequest)
 	if err := dec(in); err != nil {
@@ -184,6 +346,132 @@ func _StorageAgent_DetachVolume_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StorageAgent_CreateSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAgentServer).CreateSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/storageagent.v1.StorageAgent/CreateSnapshot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAgentServer).CreateSnapshot(ctx, req.(*SnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAgent_DeleteSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAgentServer).DeleteSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/storageagent.v1.StorageAgent/DeleteSnapshot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAgentServer).DeleteSnapshot(ctx, req.(*SnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAgent_ListSnapshots_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSnapshotsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAgentServer).ListSnapshots(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/storageagent.v1.StorageAgent/ListSnapshots",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAgentServer).ListSnapshots(ctx, req.(*ListSnapshotsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAgent_CloneImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CloneRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAgentServer).CloneImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/storageagent.v1.StorageAgent/CloneImage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAgentServer).CloneImage(ctx, req.(*CloneRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAgent_ResizeImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAgentServer).ResizeImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/storageagent.v1.StorageAgent/ResizeImage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAgentServer).ResizeImage(ctx, req.(*ResizeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAgent_GetCapacity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CapacityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAgentServer).GetCapacity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/storageagent.v1.StorageAgent/GetCapacity",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAgentServer).GetCapacity(ctx, req.(*CapacityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAgent_ModifyImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAgentServer).ModifyImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/storageagent.v1.StorageAgent/ModifyImage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAgentServer).ModifyImage(ctx, req.(*ModifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // StorageAgent_ServiceDesc is the grpc.ServiceDesc for StorageAgent service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -199,6 +487,14 @@ var StorageAgent_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteImage",
 			Handler:    _StorageAgent_DeleteImage_Handler,
 		},
+		{
+			MethodName: "GetImage",
+			Handler:    _StorageAgent_GetImage_Handler,
+		},
+		{
+			MethodName: "ListImages",
+			Handler:    _StorageAgent_ListImages_Handler,
+		},
 		{
 			MethodName: "AttachVolume",
 			Handler:    _StorageAgent_AttachVolume_Handler,
@@ -207,6 +503,34 @@ var StorageAgent_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DetachVolume",
 			Handler:    _StorageAgent_DetachVolume_Handler,
 		},
+		{
+			MethodName: "CreateSnapshot",
+			Handler:    _StorageAgent_CreateSnapshot_Handler,
+		},
+		{
+			MethodName: "DeleteSnapshot",
+			Handler:    _StorageAgent_DeleteSnapshot_Handler,
+		},
+		{
+			MethodName: "ListSnapshots",
+			Handler:    _StorageAgent_ListSnapshots_Handler,
+		},
+		{
+			MethodName: "CloneImage",
+			Handler:    _StorageAgent_CloneImage_Handler,
+		},
+		{
+			MethodName: "ResizeImage",
+			Handler:    _StorageAgent_ResizeImage_Handler,
+		},
+		{
+			MethodName: "GetCapacity",
+			Handler:    _StorageAgent_GetCapacity_Handler,
+		},
+		{
+			MethodName: "ModifyImage",
+			Handler:    _StorageAgent_ModifyImage_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "storage_agent.proto",